@@ -0,0 +1,162 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/kuolc/oneLeg/scheduler"
+	"github.com/labstack/echo"
+)
+
+type AdminHandler struct {
+	scheduler *scheduler.Scheduler
+	jobs      *Jobs
+	handler   *AppHandler
+}
+
+func NewAdminHandler(scheduler *scheduler.Scheduler, jobs *Jobs, handler *AppHandler) *AdminHandler {
+	return &AdminHandler{
+		scheduler: scheduler,
+		jobs:      jobs,
+		handler:   handler,
+	}
+}
+
+func (a *AdminHandler) jobError(err error) error {
+	if err == scheduler.ErrJobNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, "Job not found")
+	}
+	return err
+}
+
+func (a *AdminHandler) ListJobs(c echo.Context) error {
+	return c.JSON(http.StatusOK, a.scheduler.List())
+}
+
+func (a *AdminHandler) TriggerJob(c echo.Context) error {
+	err := a.scheduler.Trigger(c.Param("name"))
+	if err != nil {
+		return a.jobError(err)
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+func (a *AdminHandler) PauseJob(c echo.Context) error {
+	err := a.scheduler.Pause(c.Param("name"))
+	if err != nil {
+		return a.jobError(err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (a *AdminHandler) ResumeJob(c echo.Context) error {
+	err := a.scheduler.Resume(c.Param("name"))
+	if err != nil {
+		return a.jobError(err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (a *AdminHandler) UpcomingProblems(c echo.Context) error {
+	n, err := strconv.Atoi(c.QueryParam("n"))
+	if err != nil || n <= 0 {
+		n = 5
+	}
+
+	cohort := cohortName(c.QueryParam("cohort"))
+
+	// Blocked days are dropped from the runs, so look further ahead than n.
+	runs, err := a.scheduler.NextRuns(cohortJobName("push_problem", cohort), n*3+14)
+	if err != nil {
+		return a.jobError(err)
+	}
+
+	days, problems, err := a.jobs.Upcoming(c.Request().Context(), cohort, runs)
+	if err != nil {
+		return err
+	}
+
+	type Upcoming struct {
+		At         time.Time `json:"at"`
+		Index      int       `json:"index"`
+		Text       string    `json:"text"`
+		Setter     string    `json:"setter"`
+		Difficulty int       `json:"difficulty"`
+	}
+
+	upcoming := []*Upcoming{}
+	for index, problem := range problems {
+		if index >= n {
+			break
+		}
+
+		upcoming = append(upcoming, &Upcoming{
+			At:         days[index],
+			Index:      problem.Index,
+			Text:       problem.Text,
+			Setter:     problem.Setter,
+			Difficulty: problem.Difficulty,
+		})
+	}
+
+	return c.JSON(http.StatusOK, upcoming)
+}
+
+func (a *AdminHandler) ListGroups(c echo.Context) error {
+	groups, err := a.handler.Groups(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	type GroupSummary struct {
+		ID          string `json:"id"`
+		BotName     string `json:"botName"`
+		Status      string `json:"status"`
+		Cohort      string `json:"cohort"`
+		MemberCount int    `json:"memberCount"`
+	}
+
+	statuses := []*GroupSummary{}
+	for _, group := range groups {
+		if status := c.QueryParam("status"); status != "" && status != group.Status {
+			continue
+		}
+
+		statuses = append(statuses, &GroupSummary{
+			ID:          group.ID,
+			BotName:     group.BotName,
+			Status:      group.Status,
+			Cohort:      cohortName(group.Cohort),
+			MemberCount: len(group.MemberIDs),
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].ID < statuses[j].ID
+	})
+
+	return c.JSON(http.StatusOK, statuses)
+}
+
+func (a *AdminHandler) setGroupStatus(c echo.Context, status GroupStatus) error {
+	err := a.handler.SetGroupStatus(c.Request().Context(), c.Param("groupID"), status, c.FormValue("cohort"))
+	if err == ErrNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, "Group not found")
+	}
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (a *AdminHandler) ApproveGroup(c echo.Context) error {
+	return a.setGroupStatus(c, GroupStatusApproved)
+}
+
+func (a *AdminHandler) DeactivateGroup(c echo.Context) error {
+	return a.setGroupStatus(c, GroupStatusInactive)
+}
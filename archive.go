@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/kuolc/oneLeg/consts"
+)
+
+// archivePageSize leaves room for the next page bubble in a carousel.
+const archivePageSize = maxCarouselBubbles - 1
+
+// ArchiveQuery filters closed problems. Fields left empty match every
+// problem.
+type ArchiveQuery struct {
+	MinDifficulty int
+	MaxDifficulty int
+	Setter        string
+	Page          int
+}
+
+// ParseArchiveQuery reads words such as 難易度=3, 難易度=2-4, 出題者=山田 or
+// ページ=2. A bare number is a page and any other bare word is a setter.
+func ParseArchiveQuery(words []string) *ArchiveQuery {
+	query := &ArchiveQuery{Page: 1}
+	for _, word := range words {
+		word = normalizeWidth(word)
+
+		key, value := "", word
+		if index := strings.Index(word, "="); index > 0 {
+			key, value = word[:index], word[index+1:]
+		} else if _, err := strconv.Atoi(word); err == nil {
+			key = "ページ"
+		} else {
+			key = "出題者"
+		}
+
+		switch key {
+		case "難易度", "difficulty":
+			query.MinDifficulty, query.MaxDifficulty = parseDifficultyRange(value)
+		case "出題者", "setter":
+			query.Setter = value
+		case "ページ", "page":
+			if page, err := strconv.Atoi(value); err == nil && page > 0 {
+				query.Page = page
+			}
+		}
+	}
+	return query
+}
+
+func parseDifficultyRange(s string) (int, int) {
+	s = strings.Replace(s, "〜", "-", 1)
+	s = strings.Replace(s, "~", "-", 1)
+
+	bounds := strings.SplitN(s, "-", 2)
+	min, err := strconv.Atoi(bounds[0])
+	if err != nil {
+		return 0, 0
+	}
+
+	max := min
+	if len(bounds) == 2 {
+		max, err = strconv.Atoi(bounds[1])
+		if err != nil {
+			return 0, 0
+		}
+	}
+	return min, max
+}
+
+func (q *ArchiveQuery) Match(problem *Problem) bool {
+	if q.MinDifficulty > 0 && problem.Difficulty < q.MinDifficulty {
+		return false
+	}
+	if q.MaxDifficulty > 0 && problem.Difficulty > q.MaxDifficulty {
+		return false
+	}
+	if q.Setter != "" && !strings.Contains(problem.Setter, q.Setter) {
+		return false
+	}
+	return true
+}
+
+// Words turns q back into command words, as carried by the next page button.
+func (q *ArchiveQuery) Words() []string {
+	words := []string{}
+	if q.MinDifficulty > 0 {
+		words = append(words, fmt.Sprintf("難易度=%d-%d", q.MinDifficulty, q.MaxDifficulty))
+	}
+	if q.Setter != "" {
+		words = append(words, "出題者="+q.Setter)
+	}
+	return append(words, fmt.Sprintf("ページ=%d", q.Page))
+}
+
+// archivedProblems lists the closed problems of cohort, newest first. The
+// open problem is left out so that the archive does not spoil it.
+func (h *AppHandler) archivedProblems(ctx context.Context, cohort string) ([]*Problem, error) {
+	problems, err := h.problemStore.List(ctx)
+	if err != nil {
+		return []*Problem{}, err
+	}
+
+	archived := []*Problem{}
+	for _, problem := range problems {
+		if cohortName(problem.Cohort) == cohort && h.roundOf(problem.ID) == nil {
+			archived = append(archived, problem)
+		}
+	}
+	return archived, nil
+}
+
+func (h *AppHandler) commandArchive(c *CommandContext) error {
+	return h.replyArchive(c, ParseArchiveQuery(c.Args))
+}
+
+func (h *AppHandler) replyArchive(c *CommandContext, query *ArchiveQuery) error {
+	// In a 1:1 chat, the archive follows the group the user answers in.
+	groupID := c.Event.Source.GroupID
+	if groupID == "" {
+		if user, err := h.userStore.Get(c, c.Event.Source.UserID); err == nil {
+			groupID = user.GroupID
+		}
+	}
+
+	problems, err := h.archivedProblems(c, h.cohortOf(c, groupID))
+	if err != nil {
+		return err
+	}
+
+	matched := []*Problem{}
+	for _, problem := range problems {
+		if query.Match(problem) {
+			matched = append(matched, problem)
+		}
+	}
+
+	start := (query.Page - 1) * archivePageSize
+	if start >= len(matched) {
+		return c.ReplyText("条件に合う過去問が見つかりませんでした。")
+	}
+
+	end := start + archivePageSize
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	cards := []map[string]interface{}{}
+	for _, problem := range matched[start:end] {
+		date := ""
+		if !problem.CreatedAt.IsZero() {
+			date = problem.CreatedAt.Format("2006/1/2")
+		}
+
+		cards = append(cards, map[string]interface{}{
+			"imageURL":          problem.ProblemImageURL,
+			"date":              date,
+			"difficulty":        problem.Difficulty,
+			"setter":            problem.Setter,
+			"editorialImageURL": problem.EditorialImageURL,
+			"resultsData":       postbackData("archive", map[string]string{"problemID": problem.ID}),
+		})
+	}
+
+	next := ""
+	if end < len(matched) {
+		nextQuery := *query
+		nextQuery.Page++
+		data := url.Values{"action": {"archive"}, "word": nextQuery.Words()}
+		next = data.Encode()
+	}
+
+	altText := fmt.Sprintf("過去問（%d〜%d件目／%d件）", start+1, end, len(matched))
+	return h.replyFlexMessage(c.Client, c.Event.ReplyToken, altText, consts.ArchiveTemplatePath(), map[string]interface{}{
+		"problems": cards,
+		"next":     next,
+	})
+}
+
+// replyArchiveProblem shows the final answer distribution of every group
+// together with the editorial, headed by feedback if any.
+func (h *AppHandler) replyArchiveProblem(c *CommandContext, problemID ProblemID, feedback string) error {
+	if h.roundOf(problemID) != nil {
+		return c.ReplyText("この問題はまだ回答を受け付けています。")
+	}
+
+	problem, err := h.problemStore.Get(c, problemID)
+	if err == ErrNotFound {
+		return c.ReplyText("問題が見つかりませんでした。")
+	} else if err != nil {
+		return err
+	}
+
+	answers, err := h.answerStore.ListByProblem(c, problemID)
+	if err != nil {
+		return err
+	}
+
+	results, _ := summarizeAnswers(problem, answers)
+
+	return h.replyFlexMessage(c.Client, c.Event.ReplyToken, "過去問の回答結果", consts.ArchiveProblemTemplatePath(), map[string]interface{}{
+		"feedback":          feedback,
+		"imageURL":          problem.ProblemImageURL,
+		"text":              problem.Text,
+		"editorial":         problem.Editorial,
+		"editorialImageURL": problem.EditorialImageURL,
+		"count":             len(answers),
+		"results":           results,
+		"hasCorrect":        problem.HasCorrectOptions(),
+		"correctRate":       correctRate(problem, answers),
+	})
+}
+
+func (h *AppHandler) postbackArchive(c *CommandContext, data url.Values) error {
+	if problemID := data.Get("problemID"); problemID != "" {
+		return h.replyArchiveProblem(c, problemID, "")
+	}
+
+	return h.replyArchive(c, ParseArchiveQuery(data["word"]))
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/kuolc/oneLeg/consts"
+	"github.com/kuolc/oneLeg/json_"
+)
+
+type Bot struct {
+	Name               string    `json:"name"`
+	DisplayName        string    `json:"displayName"`
+	ChannelSecret      string    `json:"channelSecret"`
+	ChannelAccessToken string    `json:"channelAccessToken"`
+	Targets            []*Target `json:"targets"`
+}
+
+type Target struct {
+	GroupID     string `json:"groupID"`
+	DisplayName string `json:"displayName"`
+	Cohort      string `json:"cohort"`
+	Enabled     *bool  `json:"enabled"`
+
+	// AnswerButtons adds a postback button per option to the problem so
+	// that members can answer without opening LIFF.
+	AnswerButtons bool `json:"answerButtons"`
+}
+
+func (t *Target) IsEnabled() bool {
+	return t.GroupID != "" && (t.Enabled == nil || *t.Enabled)
+}
+
+type BotRegistry struct {
+	bots []*Bot
+}
+
+var legacyBotNames = []string{
+	"CHIMPANZEE", "CRAB", "RABBIT", "HAMSTER", "BUFFALO",
+}
+
+// LoadBotRegistry reads the bot config at path. Without a config, the bots
+// named in legacyBotNames are read from the environment as before. Secrets
+// left empty in the config also fall back to <NAME>_SECRET and
+// <NAME>_ACCESS_TOKEN.
+func LoadBotRegistry(path string) (*BotRegistry, error) {
+	registry := &BotRegistry{bots: []*Bot{}}
+
+	if path == "" {
+		for _, name := range legacyBotNames {
+			registry.bots = append(registry.bots, &Bot{
+				Name: name,
+				Targets: []*Target{
+					{GroupID: consts.GroupID(name)},
+				},
+			})
+		}
+	} else {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return registry, err
+		}
+
+		config := struct {
+			Bots []*Bot `json:"bots"`
+		}{}
+
+		err = json_.Unmarshal(b, &config)
+		if err != nil {
+			return registry, err
+		}
+
+		registry.bots = config.Bots
+	}
+
+	for _, bot := range registry.bots {
+		if bot.ChannelSecret == "" {
+			bot.ChannelSecret = consts.ChannelSecret(bot.Name)
+		}
+
+		if bot.ChannelAccessToken == "" {
+			bot.ChannelAccessToken = consts.ChannelAccessToken(bot.Name)
+		}
+
+		if bot.DisplayName == "" {
+			bot.DisplayName = bot.Name
+		}
+
+		for _, target := range bot.Targets {
+			if target.DisplayName == "" {
+				target.DisplayName = target.GroupID
+			}
+		}
+	}
+
+	return registry, nil
+}
+
+func (r *BotRegistry) Bots() []*Bot {
+	return r.bots
+}
+
+func (r *BotRegistry) Get(name string) (*Bot, bool) {
+	for _, bot := range r.bots {
+		if strings.EqualFold(bot.Name, name) {
+			return bot, true
+		}
+	}
+	return nil, false
+}
+
+type PushTarget struct {
+	Bot *Bot
+	*Target
+}
+
+// Targets lists the enabled groups of every bot that can push messages.
+func (r *BotRegistry) Targets() []*PushTarget {
+	targets := []*PushTarget{}
+	for _, bot := range r.bots {
+		if bot.ChannelAccessToken == "" {
+			continue
+		}
+
+		for _, target := range bot.Targets {
+			if target.IsEnabled() {
+				targets = append(targets, &PushTarget{Bot: bot, Target: target})
+			}
+		}
+	}
+	return targets
+}
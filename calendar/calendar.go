@@ -0,0 +1,127 @@
+package calendar
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+type EditorialPolicy = string
+
+const (
+	// EditorialPolicySkip closes the open problem without an editorial when
+	// the editorial falls on a blocked day.
+	EditorialPolicySkip = "skip"
+	// EditorialPolicyShift keeps the problem open and pushes its editorial on
+	// the next day that is not blocked.
+	EditorialPolicyShift = "shift"
+)
+
+type Blackout struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason"`
+}
+
+type Config struct {
+	SkipHolidays    bool            `json:"skipHolidays"`
+	EditorialPolicy EditorialPolicy `json:"editorialPolicy"`
+	Blackouts       []*Blackout     `json:"blackouts"`
+}
+
+type Calendar struct {
+	config   *Config
+	location *time.Location
+}
+
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{
+		SkipHolidays:    true,
+		EditorialPolicy: EditorialPolicyShift,
+		Blackouts:       []*Blackout{},
+	}
+
+	if path == "" {
+		return config, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+
+	err = json.Unmarshal(b, config)
+	return config, err
+}
+
+func New(config *Config, location *time.Location) (*Calendar, error) {
+	for _, blackout := range config.Blackouts {
+		from, err := time.ParseInLocation(dateLayout, blackout.From, location)
+		if err != nil {
+			return nil, err
+		}
+
+		to, err := time.ParseInLocation(dateLayout, blackout.To, location)
+		if err != nil {
+			return nil, err
+		}
+
+		if to.Before(from) {
+			return nil, fmt.Errorf("blackout %s ends before it starts", blackout.Reason)
+		}
+	}
+
+	switch config.EditorialPolicy {
+	case EditorialPolicySkip, EditorialPolicyShift:
+	default:
+		return nil, fmt.Errorf("unknown editorial policy %q", config.EditorialPolicy)
+	}
+
+	return &Calendar{
+		config:   config,
+		location: location,
+	}, nil
+}
+
+func (c *Calendar) EditorialPolicy() EditorialPolicy {
+	return c.config.EditorialPolicy
+}
+
+func HolidayName(t time.Time) (string, bool) {
+	name, ok := holidays[t.Format(dateLayout)]
+	return name, ok
+}
+
+// HasHolidays reports whether the holidays of year are listed. Outside these
+// years every day looks like a working day.
+func HasHolidays(year int) bool {
+	return firstHolidayYear <= year && year <= lastHolidayYear
+}
+
+// Blocked reports whether jobs should not run on the day of t, with a
+// human-readable reason.
+func (c *Calendar) Blocked(t time.Time) (bool, string) {
+	date := t.In(c.location).Format(dateLayout)
+
+	for _, blackout := range c.config.Blackouts {
+		if blackout.From <= date && date <= blackout.To {
+			return true, "blackout " + blackout.Reason
+		}
+	}
+
+	if c.config.SkipHolidays {
+		if year := t.In(c.location).Year(); !HasHolidays(year) {
+			log.Printf("holidays of %d are not listed, so %s is treated as a working day", year, date)
+		}
+
+		if name, ok := holidays[date]; ok {
+			return true, "holiday " + name
+		}
+	}
+
+	return false, ""
+}
@@ -0,0 +1,98 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBlockedHolidays(t *testing.T) {
+	location, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calendar, err := New(&Config{SkipHolidays: true, EditorialPolicy: EditorialPolicyShift}, location)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		date    string
+		blocked bool
+		reason  string
+	}{
+		{"2020-01-01", true, "holiday 元日"},
+		{"2020-02-24", true, "holiday 振替休日"},
+		{"2020-05-06", true, "holiday 振替休日"},
+		{"2021-07-23", true, "holiday スポーツの日"},
+		{"2021-08-09", true, "holiday 振替休日"},
+		{"2026-09-22", true, "holiday 国民の休日"},
+		{"2029-04-30", true, "holiday 振替休日"},
+		{"2030-11-04", true, "holiday 振替休日"},
+		{"2020-07-20", false, ""},
+		{"2021-08-10", false, ""},
+		{"2031-01-01", false, ""},
+	}
+
+	for _, test := range tests {
+		day, err := time.ParseInLocation(dateLayout, test.date, location)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Noon in Tokyo is still the same day in UTC, but the evening is not.
+		for _, hour := range []int{12, 21} {
+			blocked, reason := calendar.Blocked(day.Add(time.Duration(hour) * time.Hour).UTC())
+			if blocked != test.blocked || reason != test.reason {
+				t.Errorf("Blocked(%s %d:00) = %t, %q, want %t, %q", test.date, hour, blocked, reason, test.blocked, test.reason)
+			}
+		}
+	}
+}
+
+func TestBlockedBlackout(t *testing.T) {
+	calendar, err := New(&Config{
+		EditorialPolicy: EditorialPolicySkip,
+		Blackouts:       []*Blackout{{From: "2020-08-01", To: "2020-08-03", Reason: "合宿"}},
+	}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		date    string
+		blocked bool
+	}{
+		{"2020-07-31", false},
+		{"2020-08-01", true},
+		{"2020-08-03", true},
+		{"2020-08-04", false},
+		// Holidays are not skipped without SkipHolidays.
+		{"2020-08-10", false},
+	}
+
+	for _, test := range tests {
+		day, _ := time.Parse(dateLayout, test.date)
+		if blocked, _ := calendar.Blocked(day); blocked != test.blocked {
+			t.Errorf("Blocked(%s) = %t, want %t", test.date, blocked, test.blocked)
+		}
+	}
+}
+
+func TestHasHolidays(t *testing.T) {
+	tests := []struct {
+		year int
+		want bool
+	}{
+		{2019, false},
+		{2020, true},
+		{2030, true},
+		{2031, false},
+	}
+
+	for _, test := range tests {
+		if got := HasHolidays(test.year); got != test.want {
+			t.Errorf("HasHolidays(%d) = %t, want %t", test.year, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+)
+
+const (
+	initialRating = 1500.0
+	ratingK       = 32.0
+)
+
+type Calibration struct {
+	ProblemRatings map[ProblemID]float64
+	UserRatings    map[UserID]float64
+}
+
+// calibratedDifficulty maps a problem rating to the 1 to 5 star scale of
+// 難易度. Each star spans 100 points around the initial rating.
+func calibratedDifficulty(rating float64) int {
+	difficulty := int(math.Floor((rating-initialRating)/100+0.5)) + 3
+	if difficulty < 1 {
+		return 1
+	}
+	if difficulty > 5 {
+		return 5
+	}
+	return difficulty
+}
+
+// isAgreed scores an answer as a match between the user and the problem. With
+// correct options an answer wins by being correct. Polls fall back to the
+// majority, so that a minority pick counts as the problem winning.
+func isAgreed(problem *Problem, answer *Answer) (bool, bool) {
+	if problem.HasCorrectOptions() {
+		return problem.IsCorrectOption(answer.Option), true
+	}
+
+	if len(problem.MajorityOptions) == 0 {
+		return false, false
+	}
+
+	for _, option := range problem.MajorityOptions {
+		if option == answer.Option {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// calibrate replays every answer in problem order as an Elo match between the
+// user and the problem. problems must be oldest first. The problem rating is
+// updated one answer at a time, so that it settles where the expected score
+// meets the accuracy however many users answered.
+func calibrate(problems []*Problem, answers map[ProblemID][]*Answer) *Calibration {
+	calibration := &Calibration{
+		ProblemRatings: map[ProblemID]float64{},
+		UserRatings:    map[UserID]float64{},
+	}
+
+	for _, problem := range problems {
+		problemRating := initialRating
+		played := false
+
+		answered := map[UserID]bool{}
+		for _, answer := range answers[problem.ID] {
+			isWon, ok := isAgreed(problem, answer)
+			if !ok || answered[answer.UserID] {
+				continue
+			}
+			answered[answer.UserID] = true
+			played = true
+
+			userRating, ok := calibration.UserRatings[answer.UserID]
+			if !ok {
+				userRating = initialRating
+			}
+
+			expected := 1 / (1 + math.Pow(10, (problemRating-userRating)/400))
+			score := 0.0
+			if isWon {
+				score = 1
+			}
+
+			calibration.UserRatings[answer.UserID] = userRating + ratingK*(score-expected)
+			problemRating -= ratingK * (score - expected)
+		}
+
+		if played {
+			calibration.ProblemRatings[problem.ID] = problemRating
+		}
+	}
+
+	return calibration
+}
+
+// Calibrate rates every closed problem and every user from stored answers, and
+// writes the suggested difficulties back to the problem source.
+func (h *AppHandler) Calibrate(ctx context.Context) error {
+	problems, err := h.problemStore.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	closed := []*Problem{}
+	for _, problem := range problems {
+		if h.roundOf(problem.ID) == nil {
+			closed = append(closed, problem)
+		}
+	}
+
+	sort.SliceStable(closed, func(i, j int) bool {
+		return closed[i].CreatedAt.Before(closed[j].CreatedAt)
+	})
+
+	answers := map[ProblemID][]*Answer{}
+	for _, problem := range closed {
+		answers[problem.ID], err = h.answerStore.ListByProblem(ctx, problem.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	calibration := calibrate(closed, answers)
+
+	difficulties := map[string]map[int]int{}
+	for _, problem := range closed {
+		rating, ok := calibration.ProblemRatings[problem.ID]
+		if !ok {
+			continue
+		}
+
+		difficulty := calibratedDifficulty(rating)
+		err = h.problemStore.SaveCalibration(ctx, problem.ID, rating, difficulty)
+		if err != nil {
+			return err
+		}
+
+		if difficulties[problem.Pool] == nil {
+			difficulties[problem.Pool] = map[int]int{}
+		}
+		difficulties[problem.Pool][problem.Index] = difficulty
+	}
+
+	for userID, rating := range calibration.UserRatings {
+		err = h.setUserRating(ctx, userID, rating)
+		if err != nil {
+			log.Printf(`
+				Failed to save user rating
+					userID: %s
+					message %s
+			`, userID, err.Error())
+		}
+	}
+
+	for pool, poolDifficulties := range difficulties {
+		err = h.problemSource.SetCalibratedDifficulties(ctx, pool, poolDifficulties)
+		if err != nil {
+			log.Printf(`
+				Failed to write calibrated difficulties
+					pool: %s
+					message %s
+			`, pool, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (h *AppHandler) setUserRating(ctx context.Context, userID UserID, rating float64) error {
+	return h.userStore.Update(ctx, userID, map[string]interface{}{"rating": rating})
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/kuolc/oneLeg/json_"
+	"github.com/kuolc/oneLeg/scheduler"
+)
+
+const DefaultCohortName = "default"
+
+// Cohort is a set of groups sharing one daily problem. Each cohort draws from
+// its own pool, which is a problem sheet (or local file) name, with its own
+// selection and schedule. Cohorts sharing a pool also share submitted marks.
+type Cohort struct {
+	Name      string                          `json:"name"`
+	Pool      string                          `json:"pool"`
+	Selection *SelectionConfig                `json:"selection"`
+	Schedule  map[string]*scheduler.JobConfig `json:"schedule"`
+
+	selector *Selector
+}
+
+func (c *Cohort) JobName(job string) string {
+	return cohortJobName(job, c.Name)
+}
+
+// JobConfig overrides config with the cohort schedule of job, if any.
+func (c *Cohort) JobConfig(job string, config *scheduler.JobConfig) *scheduler.JobConfig {
+	override, ok := c.Schedule[job]
+	if !ok {
+		return config
+	}
+
+	merged := *override
+	if config != nil {
+		if merged.Spec == "" {
+			merged.Spec = config.Spec
+		}
+
+		if merged.CatchUp == "" {
+			merged.CatchUp = config.CatchUp
+		}
+	}
+
+	return &merged
+}
+
+// LoadCohorts reads the cohort config at path. Cohorts without a selection
+// use the given one, and the default cohort always exists.
+func LoadCohorts(path string, selection *SelectionConfig, location *time.Location) ([]*Cohort, error) {
+	config := struct {
+		Cohorts []*Cohort `json:"cohorts"`
+	}{}
+
+	if path != "" {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return []*Cohort{}, err
+		}
+
+		err = json_.Unmarshal(b, &config)
+		if err != nil {
+			return []*Cohort{}, err
+		}
+	}
+
+	hasDefault := false
+	names := map[string]bool{}
+	for _, cohort := range config.Cohorts {
+		if cohort.Name == "" {
+			return []*Cohort{}, fmt.Errorf("cohort name is empty")
+		}
+
+		if names[cohort.Name] {
+			return []*Cohort{}, fmt.Errorf("cohort %q is duplicated", cohort.Name)
+		}
+		names[cohort.Name] = true
+
+		hasDefault = hasDefault || cohort.Name == DefaultCohortName
+	}
+
+	if !hasDefault {
+		config.Cohorts = append([]*Cohort{{Name: DefaultCohortName}}, config.Cohorts...)
+	}
+
+	for _, cohort := range config.Cohorts {
+		if cohort.Selection == nil {
+			cohort.Selection = selection
+		}
+
+		selector, err := NewSelector(cohort.Selection, location)
+		if err != nil {
+			return []*Cohort{}, fmt.Errorf("cohort %s: %s", cohort.Name, err.Error())
+		}
+		cohort.selector = selector
+	}
+
+	return config.Cohorts, nil
+}
+
+// cohortJobName is the scheduler job name of job for cohort. The default
+// cohort keeps the plain job names.
+func cohortJobName(job string, cohort string) string {
+	if cohort == DefaultCohortName {
+		return job
+	}
+	return job + "/" + cohort
+}
+
+func cohortName(name string) string {
+	if name == "" {
+		return DefaultCohortName
+	}
+	return name
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"unicode"
+
+	"github.com/kuolc/oneLeg/consts"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+type CommandPermission = string
+
+const (
+	CommandPermissionEveryone = "everyone"
+	CommandPermissionAdmin    = "admin"
+)
+
+type CommandScope = string
+
+const (
+	CommandScopeAny    = "any"
+	CommandScopeGroup  = "group"
+	CommandScopeDirect = "direct"
+)
+
+type Command struct {
+	Name    string
+	Aliases []string
+	// Usage is the argument syntax shown after the name in ヘルプ.
+	Usage      string
+	Help       string
+	Permission CommandPermission
+	Scope      CommandScope
+	// Hidden commands answer quick replies and are left out of ヘルプ.
+	Hidden bool
+	Run    func(c *CommandContext) error
+}
+
+func (command *Command) matches(name string) bool {
+	if strings.EqualFold(command.Name, name) {
+		return true
+	}
+
+	for _, alias := range command.Aliases {
+		if strings.EqualFold(alias, name) {
+			return true
+		}
+	}
+	return false
+}
+
+type CommandContext struct {
+	context.Context
+	Bot    *Bot
+	Client *linebot.Client
+	Event  *linebot.Event
+	Args   []string
+}
+
+func (c *CommandContext) Reply(messages ...linebot.SendingMessage) error {
+	_, err := c.Client.ReplyMessage(c.Event.ReplyToken, messages...).Do()
+	return err
+}
+
+func (c *CommandContext) ReplyText(text string) error {
+	return c.Reply(linebot.NewTextMessage(text))
+}
+
+func (c *CommandContext) IsAdmin() bool {
+	for _, userID := range consts.AdminUserIDs() {
+		if userID == c.Event.Source.UserID {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CommandContext) IsDirect() bool {
+	return c.Event.Source.Type == linebot.EventSourceTypeUser
+}
+
+// Allows tells whether the sender may run command here.
+func (c *CommandContext) Allows(command *Command) bool {
+	if command.Permission == CommandPermissionAdmin && !c.IsAdmin() {
+		return false
+	}
+
+	switch command.Scope {
+	case CommandScopeGroup:
+		return !c.IsDirect()
+	case CommandScopeDirect:
+		return c.IsDirect()
+	}
+	return true
+}
+
+type CommandRouter struct {
+	commands []*Command
+}
+
+func NewCommandRouter() *CommandRouter {
+	return &CommandRouter{commands: []*Command{}}
+}
+
+func (r *CommandRouter) Register(command *Command) {
+	if command.Permission == "" {
+		command.Permission = CommandPermissionEveryone
+	}
+
+	if command.Scope == "" {
+		command.Scope = CommandScopeAny
+	}
+
+	r.commands = append(r.commands, command)
+}
+
+func (r *CommandRouter) Commands() []*Command {
+	return r.commands
+}
+
+func (r *CommandRouter) Find(name string) (*Command, bool) {
+	for _, command := range r.commands {
+		if command.matches(name) {
+			return command, true
+		}
+	}
+	return nil, false
+}
+
+// Dispatch runs the command named by the first word of text. Text that is not
+// a command is ignored, since the bots share groups with ordinary talk.
+func (r *CommandRouter) Dispatch(c *CommandContext, text string) error {
+	words := parseCommandLine(text)
+	if len(words) == 0 {
+		return nil
+	}
+
+	command, ok := r.Find(words[0])
+	if !ok || !c.Allows(command) {
+		return nil
+	}
+
+	c.Args = words[1:]
+	return command.Run(c)
+}
+
+// parseCommandLine splits text into words. Half- and full-width spaces and
+// colons separate words, and "..." or 「...」 keep spaces within a word.
+func parseCommandLine(text string) []string {
+	words := []string{}
+	word := []rune{}
+	var closing rune
+	hasWord := false
+
+	flush := func() {
+		if hasWord {
+			words = append(words, string(word))
+		}
+		word = []rune{}
+		hasWord = false
+	}
+
+	for _, r := range strings.TrimSpace(text) {
+		switch {
+		case closing != 0:
+			if r == closing {
+				closing = 0
+			} else {
+				word = append(word, r)
+			}
+		case r == '"' || r == '“':
+			closing, hasWord = '"', true
+			if r == '“' {
+				closing = '”'
+			}
+		case r == '「':
+			closing, hasWord = '」', true
+		case unicode.IsSpace(r) || r == ':' || r == '：':
+			flush()
+		default:
+			word = append(word, r)
+			hasWord = true
+		}
+	}
+	flush()
+
+	if len(words) > 0 {
+		words[0] = strings.TrimLeft(words[0], "/／")
+	}
+
+	return words
+}
+
+// helpText lists the commands c may run, or describes one of them.
+func (r *CommandRouter) helpText(c *CommandContext) string {
+	if len(c.Args) > 0 {
+		command, ok := r.Find(c.Args[0])
+		if !ok || !c.Allows(command) {
+			return fmt.Sprintf("「%s」というコマンドはありません。", c.Args[0])
+		}
+
+		lines := []string{commandSyntax(command), command.Help}
+		if len(command.Aliases) > 0 {
+			lines = append(lines, "別名："+strings.Join(command.Aliases, "、"))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	lines := []string{"使えるコマンド"}
+	for _, command := range r.commands {
+		if command.Hidden || !c.Allows(command) {
+			continue
+		}
+		lines = append(lines, "・"+commandSyntax(command), "　"+command.Help)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func commandSyntax(command *Command) string {
+	if command.Usage == "" {
+		return command.Name
+	}
+	return command.Name + " " + command.Usage
+}
+
+func (h *AppHandler) newCommandRouter() *CommandRouter {
+	r := NewCommandRouter()
+
+	r.Register(&Command{
+		Name:    "ヘルプ",
+		Aliases: []string{"help"},
+		Usage:   "[コマンド]",
+		Help:    "コマンドの一覧や使い方を表示します。",
+		Run: func(c *CommandContext) error {
+			return c.ReplyText(r.helpText(c))
+		},
+	})
+
+	r.Register(&Command{
+		Name: "設定",
+		Help: "名前の公開やリマインダーなどの設定を変更します。個別チャットで使えます。",
+		Run:  h.replySettings,
+	})
+
+	r.Register(&Command{
+		Name:  "ニックネーム",
+		Usage: "名前|解除",
+		Help:  "回答者として表示する名前を設定します。",
+		Run:   h.commandNickname,
+	})
+
+	r.Register(&Command{
+		Name:    "成績",
+		Aliases: []string{"stats"},
+		Help:    "これまでの回答の成績を表示します。",
+		Run:     h.replyStats,
+	})
+
+	r.Register(&Command{
+		Name:    "地図",
+		Aliases: []string{"map"},
+		Usage:   "[年度 競技形式 キーワード 大会=… 名前=… 一覧]",
+		Help:    "条件に合う地図をランダムに1枚紹介します。年度は2015-2020のように範囲でも指定できます。一覧を付けると複数件を表示します。",
+		Run:     h.commandMap,
+	})
+
+	r.Register(&Command{
+		Name:    "過去問",
+		Aliases: []string{"archive"},
+		Usage:   "[難易度=… 出題者=… ページ=…]",
+		Help:    "解説済みの問題を新しい順に表示します。難易度は2-4のように範囲でも指定できます。",
+		Run:     h.commandArchive,
+	})
+
+	r.Register(&Command{
+		Name:    "練習",
+		Aliases: []string{"practice"},
+		Help:    "まだ回答していない過去問を1問出題します。練習の回答は成績に含まれません。",
+		Scope:   CommandScopeDirect,
+		Run:     h.commandPractice,
+	})
+
+	r.Register(&Command{
+		Name:       "出題",
+		Help:       "このグループのコホートに問題をすぐに出題します。",
+		Permission: CommandPermissionAdmin,
+		Scope:      CommandScopeGroup,
+		Run: func(c *CommandContext) error {
+			return h.PushProblem(c, h.cohortOf(c, c.Event.Source.GroupID))
+		},
+	})
+
+	r.Register(&Command{
+		Name:       "解説",
+		Help:       "このグループのコホートの解説をすぐに配信します。",
+		Permission: CommandPermissionAdmin,
+		Scope:      CommandScopeGroup,
+		Run: func(c *CommandContext) error {
+			return h.PushEditorial(c, h.cohortOf(c, c.Event.Source.GroupID))
+		},
+	})
+
+	return r
+}
+
+func (h *AppHandler) commandNickname(c *CommandContext) error {
+	if len(c.Args) == 0 {
+		return c.ReplyText("「ニックネーム 名前」の形で送信してください。")
+	}
+
+	nickname := strings.Join(c.Args, " ")
+	if nickname == "解除" {
+		nickname = ""
+	}
+
+	if len([]rune(nickname)) > 20 {
+		return c.ReplyText("ニックネームは20文字以内にしてください。")
+	}
+
+	err := h.userStore.Update(c, c.Event.Source.UserID, map[string]interface{}{"nickname": nickname})
+
+	if err != nil {
+		return err
+	}
+
+	if nickname == "" {
+		return c.ReplyText("ニックネームを解除しました！")
+	}
+	return c.ReplyText("ニックネームを「" + nickname + "」にしました！")
+}
+
+func (h *AppHandler) commandMap(c *CommandContext) error {
+	omaps := h.omaps()
+	if len(omaps) == 0 {
+		return c.ReplyText("地図が登録されていません。")
+	}
+
+	query := ParseMapQuery(c.Args, omaps)
+	maps := SearchMaps(omaps, query)
+	if len(maps) == 0 {
+		return c.ReplyText("条件に合う地図が見つかりませんでした。")
+	}
+
+	if query.IsList {
+		shown := maps
+		if len(shown) > maxCarouselBubbles {
+			shown = shown[:maxCarouselBubbles]
+		}
+
+		return h.replyFlexMessage(c.Client, c.Event.ReplyToken, fmt.Sprintf("地図の検索結果（%d件）", len(maps)), consts.MapsTemplatePath(), map[string]interface{}{
+			"maps": shown,
+		})
+	}
+
+	omap := maps[rand.Intn(len(maps))]
+	lines := append([]string{
+		omap.Name,
+		fmt.Sprintf("%d年度 %s (%s)", omap.Year, omap.Event, omap.Regulation),
+	}, omap.URLs...)
+
+	return c.ReplyText(strings.Join(lines, "\n"))
+}
@@ -3,15 +3,18 @@ package consts
 import (
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 func init() {
+	// Every value is read with os.Getenv, so the environment alone is enough
+	// without a .env.
 	err := godotenv.Load()
 	if err != nil {
-		log.Fatalln(".env not found")
-		return
+		log.Println(".env not found")
 	}
 }
 
@@ -59,6 +62,10 @@ func GroupID(botName string) string {
 	return os.Getenv(botName + "_GROUP_ID")
 }
 
+func BotsConfigPath() string {
+	return os.Getenv("BOTS_CONFIG_PATH")
+}
+
 func ProblemTemplatePath() string {
 	return "resources/problem.jsonnet"
 }
@@ -67,6 +74,34 @@ func EditorialTemplatePath() string {
 	return "resources/editorial.jsonnet"
 }
 
+func MapsTemplatePath() string {
+	return "resources/maps.jsonnet"
+}
+
+func SettingsTemplatePath() string {
+	return "resources/settings.jsonnet"
+}
+
+func ArchiveTemplatePath() string {
+	return "resources/archive.jsonnet"
+}
+
+func ArchiveProblemTemplatePath() string {
+	return "resources/archive_problem.jsonnet"
+}
+
+func PracticeTemplatePath() string {
+	return "resources/practice.jsonnet"
+}
+
+func StatsTemplatePath() string {
+	return "resources/stats.jsonnet"
+}
+
+func LeaderboardTemplatePath() string {
+	return "resources/leaderboard.jsonnet"
+}
+
 func GoogleCredentialPath() string {
 	return os.Getenv("GOOGLE_CREDENTIAL_PATH")
 }
@@ -75,14 +110,132 @@ func SheetID() string {
 	return os.Getenv("SHEET_ID")
 }
 
-func UpdateMapsAt() int {
-	return 0
+type SourceBackend = string
+
+const (
+	SourceBackendSheets = "sheets"
+	SourceBackendLocal  = "local"
+)
+
+func Source() SourceBackend {
+	if source := os.Getenv("SOURCE"); source != "" {
+		return SourceBackend(source)
+	}
+	return SourceBackendSheets
+}
+
+func SchemaConfigPath() string {
+	return os.Getenv("SCHEMA_CONFIG_PATH")
+}
+
+func SelectionConfigPath() string {
+	return os.Getenv("SELECTION_CONFIG_PATH")
+}
+
+func LocalSourceDir() string {
+	return os.Getenv("LOCAL_SOURCE_DIR")
 }
 
-func PushProblemAt() int {
-	return 9
+func BaseURL() string {
+	return os.Getenv("BASE_URL")
 }
 
-func PushEditorialAt() int {
-	return 19
+func ScheduleConfigPath() string {
+	return os.Getenv("SCHEDULE_CONFIG_PATH")
+}
+
+func CalendarConfigPath() string {
+	return os.Getenv("CALENDAR_CONFIG_PATH")
+}
+
+func TimeZone() string {
+	return "Asia/Tokyo"
+}
+
+func UpdateMapsSpec() string {
+	return "0 0 * * *"
+}
+
+func PushProblemSpec() string {
+	return "0 9 * * 1-5"
+}
+
+func PushEditorialSpec() string {
+	return "0 19 * * *"
+}
+
+func PushWeeklyLeaderboardSpec() string {
+	return "0 20 * * 0"
+}
+
+func PushMonthlyLeaderboardSpec() string {
+	return "0 20 1 * *"
+}
+
+func RemindSpec() string {
+	return "0 17 * * *"
+}
+
+func ReviewSpec() string {
+	return "0 19 * * *"
+}
+
+func CalibrateSpec() string {
+	return "0 3 * * *"
+}
+
+func PushProblemCatchUp() string {
+	return "3h"
+}
+
+func PushEditorialCatchUp() string {
+	return "4h"
+}
+
+func SchedulerStatePath() string {
+	if path := os.Getenv("SCHEDULER_STATE_PATH"); path != "" {
+		return path
+	}
+	return "scheduler_state.json"
+}
+
+func AdminToken() string {
+	return os.Getenv("ADMIN_TOKEN")
+}
+
+type StoreBackend = string
+
+const (
+	StoreBackendFirestore = "firestore"
+	StoreBackendMemory    = "memory"
+)
+
+func Store() StoreBackend {
+	if store := os.Getenv("STORE"); store != "" {
+		return StoreBackend(store)
+	}
+	return StoreBackendFirestore
+}
+
+func GoogleTimeout() time.Duration {
+	timeout, err := time.ParseDuration(os.Getenv("GOOGLE_TIMEOUT"))
+	if err != nil || timeout <= 0 {
+		return 30 * time.Second
+	}
+	return timeout
+}
+
+func CohortsConfigPath() string {
+	return os.Getenv("COHORTS_CONFIG_PATH")
+}
+
+// AdminUserIDs are the LINE users allowed to run admin chat commands.
+func AdminUserIDs() []string {
+	userIDs := []string{}
+	for _, userID := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+		if userID = strings.TrimSpace(userID); userID != "" {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	return userIDs
 }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type EditorialResult struct {
+	Option        string   `json:"option"`
+	Rate          int      `json:"rate"`
+	Count         int      `json:"count"`
+	IsMajority    bool     `json:"isMajority"`
+	IsCorrect     bool     `json:"isCorrect"`
+	Answerers     []string `json:"answerers"`
+	AnswerersText string   `json:"answerersText"`
+}
+
+type EditorialComment struct {
+	UserName string `json:"userName"`
+	Text     string `json:"text"`
+}
+
+// summarizeAnswers tallies answers per option of problem. Answers out of the
+// range of options are ignored.
+func summarizeAnswers(problem *Problem, answers []*Answer) ([]*EditorialResult, [][]*EditorialComment) {
+	results := []*EditorialResult{}
+	commentLists := [][]*EditorialComment{}
+
+	for index, option := range problem.Options {
+		results = append(results, &EditorialResult{
+			Option:    option,
+			Rate:      0,
+			Count:     0,
+			IsCorrect: problem.IsCorrectOption(index),
+		})
+
+		commentLists = append(commentLists, []*EditorialComment{})
+	}
+
+	maxCount := 0
+	for _, answer := range answers {
+		if answer.Option < 0 || answer.Option >= len(results) {
+			continue
+		}
+
+		result := results[answer.Option]
+
+		count := result.Count + 1
+		result.Count = count
+		if count > maxCount {
+			maxCount = count
+		}
+
+		if !answer.UserIsHidden {
+			result.Answerers = append(result.Answerers, answer.UserName)
+		}
+
+		results[answer.Option] = result
+
+		if answer.Comment != "" {
+			userName := answer.UserName
+			if answer.CommentIsAnonymous {
+				userName = "匿名"
+			}
+
+			commentLists[answer.Option] = append(commentLists[answer.Option], &EditorialComment{
+				UserName: userName,
+				Text:     answer.Comment,
+			})
+		}
+	}
+
+	for _, result := range results {
+		if len(answers) > 0 {
+			result.Rate = result.Count * 100 / len(answers)
+		}
+		result.IsMajority = (result.Count == maxCount)
+
+		answerers := result.Answerers
+
+		elseCount := result.Count
+		if len(answerers) > 10 {
+			elseCount -= 10
+		} else {
+			elseCount -= len(answerers)
+		}
+
+		if len(answerers) == 0 {
+			if result.Count > 0 {
+				result.AnswerersText = fmt.Sprintf("回答者%d人", elseCount)
+			} else {
+				result.AnswerersText = "回答者なし"
+			}
+		} else {
+			text := strings.Join(answerers[:result.Count-elseCount], "、")
+			if elseCount > 0 {
+				text = text + fmt.Sprintf(" 他%d人", elseCount)
+			}
+			result.AnswerersText = text
+		}
+	}
+
+	return results, commentLists
+}
+
+// correctRate is the percentage of answers choosing a correct option.
+func correctRate(problem *Problem, answers []*Answer) int {
+	if !problem.HasCorrectOptions() || len(answers) == 0 {
+		return 0
+	}
+
+	count := 0
+	for _, answer := range answers {
+		if problem.IsCorrectOption(answer.Option) {
+			count++
+		}
+	}
+	return count * 100 / len(answers)
+}
+
+func majorityOptions(results []*EditorialResult) []int {
+	options := []int{}
+	for index, result := range results {
+		if result.IsMajority && result.Count > 0 {
+			options = append(options, index)
+		}
+	}
+	return options
+}
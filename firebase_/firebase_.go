@@ -2,7 +2,6 @@ package firebase_
 
 import (
 	"context"
-	"log"
 
 	"cloud.google.com/go/firestore"
 	firebase "firebase.google.com/go"
@@ -14,30 +13,22 @@ type FirebaseClient struct {
 	Firestore *firestore.Client
 }
 
-var Client *FirebaseClient
-
-func init() {
-	ctx := context.Background()
-
+func NewClient(ctx context.Context) (*FirebaseClient, error) {
 	app, err := firebase.NewApp(ctx, nil, option.WithCredentialsFile(consts.GoogleCredentialPath()))
 	if err != nil {
-		log.Fatalln(err)
+		return nil, err
 	}
 
 	firestore, err := app.Firestore(ctx)
 	if err != nil {
-		log.Fatalln(err)
+		return nil, err
 	}
 
-	Client = &FirebaseClient{
+	return &FirebaseClient{
 		Firestore: firestore,
-	}
+	}, nil
 }
 
-// TODO: Call finalizer
-func Close() {
-	err := Client.Firestore.Close()
-	if err != nil {
-		log.Fatalln(err)
-	}
+func (c *FirebaseClient) Close() error {
+	return c.Firestore.Close()
 }
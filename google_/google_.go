@@ -0,0 +1,109 @@
+package google_
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+var Scopes = []string{
+	sheets.SpreadsheetsScope,
+	drive.DriveReadonlyScope,
+}
+
+type GoogleClient struct {
+	HTTP    *http.Client
+	Sheets  *sheets.Service
+	Drive   *drive.Service
+	timeout time.Duration
+}
+
+func NewClient(ctx context.Context, credentialPath string, timeout time.Duration) (*GoogleClient, error) {
+	b, err := ioutil.ReadFile(credentialPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := google.JWTConfigFromJSON(b, Scopes...)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenSource := oauth2.ReuseTokenSource(nil, config.TokenSource(context.Background()))
+	httpClient := oauth2.NewClient(context.Background(), tokenSource)
+	httpClient.Timeout = timeout
+
+	sheetService, err := sheets.NewService(ctx, option.WithHTTPClient(httpClient))
+	if err != nil {
+		return nil, err
+	}
+
+	driveService, err := drive.NewService(ctx, option.WithHTTPClient(httpClient))
+	if err != nil {
+		return nil, err
+	}
+
+	return &GoogleClient{
+		HTTP:    httpClient,
+		Sheets:  sheetService,
+		Drive:   driveService,
+		timeout: timeout,
+	}, nil
+}
+
+func (c *GoogleClient) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// OpenImage downloads Drive files through the API so that images shared only
+// with the service account can be read. Other URLs are fetched as they are.
+func (c *GoogleClient) OpenImage(ctx context.Context, imageURL string) (io.ReadCloser, error) {
+	ctx, cancel := c.WithTimeout(ctx)
+
+	var response *http.Response
+	var err error
+	if fileID := DriveFileID(imageURL); fileID != "" {
+		response, err = c.Drive.Files.Get(fileID).Context(ctx).Download()
+	} else {
+		var request *http.Request
+		request, err = http.NewRequest(http.MethodGet, imageURL, nil)
+		if err == nil {
+			response, err = http.DefaultClient.Do(request.WithContext(ctx))
+		}
+	}
+
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &cancelReadCloser{ReadCloser: response.Body, cancel: cancel}, nil
+}
+
+func DriveFileID(imageURL string) string {
+	u, err := url.Parse(imageURL)
+	if err != nil || !strings.HasSuffix(u.Host, "drive.google.com") {
+		return ""
+	}
+	return u.Query().Get("id")
+}
+
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelReadCloser) Close() error {
+	defer r.cancel()
+	return r.ReadCloser.Close()
+}
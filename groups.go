@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+func (h *AppHandler) handleGroupEvent(ctx context.Context, bot *Bot, lineEvent *linebot.Event) error {
+	groupID := eventGroupID(lineEvent)
+	if groupID == "" {
+		return nil
+	}
+
+	group, err := h.groupStore.Get(ctx, groupID)
+	if err == ErrNotFound {
+		group = &Group{ID: groupID}
+	} else if err != nil {
+		return err
+	}
+
+	group.BotName = bot.Name
+	switch lineEvent.Type {
+	case linebot.EventTypeJoin:
+		// Groups in the bot registry are pushed to without approval.
+		if h.isConfigured(groupID) {
+			group.Status = GroupStatusApproved
+		} else if group.Status != GroupStatusApproved {
+			group.Status = GroupStatusPending
+		}
+		log.Printf("%s: joined %s (%s)\n", bot.Name, groupID, group.Status)
+	case linebot.EventTypeLeave:
+		group.Status = GroupStatusInactive
+		log.Printf("%s: left %s\n", bot.Name, groupID)
+	case linebot.EventTypeMemberJoined:
+		if lineEvent.Joined != nil {
+			for _, member := range lineEvent.Joined.Members {
+				group.MemberIDs = addMemberID(group.MemberIDs, member.UserID)
+			}
+		}
+	case linebot.EventTypeMemberLeft:
+		if lineEvent.Left != nil {
+			for _, member := range lineEvent.Left.Members {
+				group.MemberIDs = removeMemberID(group.MemberIDs, member.UserID)
+			}
+		}
+	}
+
+	if group.Status == "" {
+		group.Status = GroupStatusPending
+	}
+
+	return h.groupStore.Save(ctx, group)
+}
+
+// eventGroupID is the group or room lineEvent came from, if any.
+func eventGroupID(lineEvent *linebot.Event) GroupID {
+	if lineEvent.Source.GroupID != "" {
+		return lineEvent.Source.GroupID
+	}
+	return lineEvent.Source.RoomID
+}
+
+func addMemberID(memberIDs []UserID, userID UserID) []UserID {
+	if userID == "" {
+		return memberIDs
+	}
+
+	for _, memberID := range memberIDs {
+		if memberID == userID {
+			return memberIDs
+		}
+	}
+	return append(memberIDs, userID)
+}
+
+func removeMemberID(memberIDs []UserID, userID UserID) []UserID {
+	filtered := []UserID{}
+	for _, memberID := range memberIDs {
+		if memberID != userID {
+			filtered = append(filtered, memberID)
+		}
+	}
+	return filtered
+}
+
+// pushTargets merges the configured targets of cohort with its approved
+// groups in the store. Groups listed in the bot registry are always governed
+// by the config.
+func (h *AppHandler) pushTargets(ctx context.Context, cohort string) []*PushTarget {
+	targets := []*PushTarget{}
+	for _, target := range h.bots.Targets() {
+		if cohortName(target.Cohort) == cohort {
+			targets = append(targets, target)
+		}
+	}
+
+	groups, err := h.groupStore.List(ctx)
+	if err != nil {
+		log.Printf(`Failed to list groups: message %s`, err.Error())
+		return targets
+	}
+
+	for _, group := range groups {
+		if group.Status != GroupStatusApproved || h.isConfigured(group.ID) || cohortName(group.Cohort) != cohort {
+			continue
+		}
+
+		bot, ok := h.bots.Get(group.BotName)
+		if !ok || bot.ChannelAccessToken == "" {
+			continue
+		}
+
+		targets = append(targets, &PushTarget{Bot: bot, Target: &Target{GroupID: group.ID, DisplayName: group.ID, Cohort: group.Cohort}})
+	}
+
+	return targets
+}
+
+// isConfigured reports whether groupID is a target in the bot registry.
+func (h *AppHandler) isConfigured(groupID GroupID) bool {
+	for _, bot := range h.bots.Bots() {
+		for _, target := range bot.Targets {
+			if target.GroupID == groupID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// botOf finds the bot in groupID, as configured or as recorded on join.
+func (h *AppHandler) botOf(ctx context.Context, groupID GroupID) (*Bot, bool) {
+	for _, bot := range h.bots.Bots() {
+		for _, target := range bot.Targets {
+			if target.GroupID == groupID {
+				return bot, true
+			}
+		}
+	}
+
+	group, err := h.groupStore.Get(ctx, groupID)
+	if err != nil {
+		return nil, false
+	}
+	return h.bots.Get(group.BotName)
+}
+
+func (h *AppHandler) Groups(ctx context.Context) ([]*Group, error) {
+	return h.groupStore.List(ctx)
+}
+
+// SetGroupStatus updates the status of a group. The group moves to cohort
+// unless it is empty.
+func (h *AppHandler) SetGroupStatus(ctx context.Context, groupID GroupID, status GroupStatus, cohort string) error {
+	if cohort != "" {
+		if _, ok := h.cohort(cohort); !ok {
+			return fmt.Errorf("cohort %s not found", cohort)
+		}
+	}
+
+	group, err := h.groupStore.Get(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	group.Status = status
+	if cohort != "" {
+		group.Cohort = cohort
+	}
+
+	return h.groupStore.Save(ctx, group)
+}
+
+// cohortOf finds the cohort of a group from the bot registry or the store.
+func (h *AppHandler) cohortOf(ctx context.Context, groupID GroupID) string {
+	if groupID == "" {
+		return DefaultCohortName
+	}
+
+	for _, bot := range h.bots.Bots() {
+		for _, target := range bot.Targets {
+			if target.GroupID == groupID {
+				return cohortName(target.Cohort)
+			}
+		}
+	}
+
+	group, err := h.groupStore.Get(ctx, groupID)
+	if err != nil {
+		return DefaultCohortName
+	}
+	return cohortName(group.Cohort)
+}
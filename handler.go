@@ -10,50 +10,92 @@ import (
 	_ "image/png"
 	"io/ioutil"
 	"log"
-	"math/rand"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
-	"cloud.google.com/go/firestore"
 	"github.com/google/go-jsonnet"
 	"github.com/kuolc/oneLeg/consts"
-	"github.com/kuolc/oneLeg/firebase_"
 	"github.com/kuolc/oneLeg/json_"
 	"github.com/line/line-bot-sdk-go/linebot"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
-	"golang.org/x/oauth2/jwt"
-	"google.golang.org/api/option"
-	"google.golang.org/api/sheets/v4"
 
 	"github.com/labstack/echo"
 )
 
 type ProblemID = string
 type UserID = string
+type GroupID = string
 
 type AppHandler struct {
-	todayProblem *Problem
-	problems     map[ProblemID]*Problem
-	answers      map[UserID]*Answer
-	maps         []*OMap
+	// mutex guards rounds, problems and maps. Answers of an open problem are
+	// owned by its round.
+	mutex    sync.RWMutex
+	rounds   map[string]*Round
+	problems map[ProblemID]*Problem
+	maps     []*OMap
+
+	problemSource ProblemSource
+	mapSource     MapSource
+	problemStore  ProblemStore
+	answerStore   AnswerStore
+	practiceStore AnswerStore
+	userStore     UserStore
+	groupStore    GroupStore
+	reviewStore   ReviewStore
+	cohorts       []*Cohort
+	bots          *BotRegistry
+	location      *time.Location
+	commands      *CommandRouter
+	postbacks     map[string]PostbackHandler
+}
+
+func NewAppHandler(problemSource ProblemSource, mapSource MapSource, stores *Stores, cohorts []*Cohort, bots *BotRegistry, location *time.Location) *AppHandler {
+	h := &AppHandler{
+		rounds:        make(map[string]*Round),
+		problems:      make(map[ProblemID]*Problem),
+		problemSource: problemSource,
+		mapSource:     mapSource,
+		problemStore:  stores.Problems,
+		answerStore:   stores.Answers,
+		practiceStore: stores.PracticeAnswers,
+		userStore:     stores.Users,
+		groupStore:    stores.Groups,
+		reviewStore:   stores.Reviews,
+		cohorts:       cohorts,
+		bots:          bots,
+		location:      location,
+	}
+
+	h.commands = h.newCommandRouter()
+	h.postbacks = h.newPostbackHandlers()
+	return h
 }
 
 type Problem struct {
-	ID                string   `json:"-"`
-	Index             int      `json:"index"`
-	Text              string   `json:"text"`
-	OriginalImageURL  string   `json:"originalImageURL"`
-	ProblemImageURL   string   `json:"problemImageURL"`
-	EditorialImageURL string   `json:"editorialImageURL"`
-	Setter            string   `json:"setter"`
-	Difficulty        int      `json:"difficulty"`
-	Options           []string `json:"options"`
-	Editorial         string   `json:"editorial"`
-	Note              string   `json:"note"`
-	HasSubmitted      bool     `json:"-"`
+	ID                   string    `json:"-"`
+	Index                int       `json:"index"`
+	Text                 string    `json:"text"`
+	OriginalImageURL     string    `json:"originalImageURL"`
+	ProblemImageURL      string    `json:"problemImageURL"`
+	EditorialImageURL    string    `json:"editorialImageURL"`
+	Setter               string    `json:"setter"`
+	Difficulty           int       `json:"difficulty"`
+	Options              []string  `json:"options"`
+	Editorial            string    `json:"editorial"`
+	Note                 string    `json:"note"`
+	ScheduledDate        string    `json:"scheduledDate"`
+	CorrectOptions       []int     `json:"correctOptions"`
+	MajorityOptions      []int     `json:"majorityOptions"`
+	Rating               float64   `json:"rating"`
+	CalibratedDifficulty int       `json:"calibratedDifficulty"`
+	Pool                 string    `json:"pool"`
+	Cohort               string    `json:"cohort"`
+	CreatedAt            time.Time `json:"-"`
+	HasSubmitted         bool      `json:"-"`
+	Row                  int       `json:"-"`
 }
 
 type Answer struct {
@@ -65,13 +107,59 @@ type Answer struct {
 	UserIsHidden bool   `json:"userIsHidden"`
 	Option       int    `json:"option"`
 	Comment      string `json:"comment"`
+	// CommentIsAnonymous hides the name on the comment only.
+	CommentIsAnonymous bool `json:"commentIsAnonymous"`
+	// IsCorrect is nil when the problem has no correct option.
+	IsCorrect *bool `json:"isCorrect"`
+}
+
+type GroupStatus = string
+
+const (
+	GroupStatusPending  = "pending"
+	GroupStatusApproved = "approved"
+	GroupStatusInactive = "inactive"
+)
+
+type Group struct {
+	ID        string      `json:"-"`
+	BotName   string      `json:"botName"`
+	Status    GroupStatus `json:"status"`
+	Cohort    string      `json:"cohort"`
+	MemberIDs []UserID    `json:"memberIDs"`
 }
 
 type User struct {
-	ID       string `json:"-"`
-	Name     string `json:"name"`
-	GroupID  string `json:"groupID"`
-	IsHidden bool   `json:"isHidden"`
+	ID       string  `json:"-"`
+	Name     string  `json:"name"`
+	GroupID  string  `json:"groupID"`
+	IsHidden bool    `json:"isHidden"`
+	Rating   float64 `json:"rating"`
+	// Nickname replaces the LINE display name in answers when set.
+	Nickname           string `json:"nickname"`
+	IsReminderEnabled  bool   `json:"isReminderEnabled"`
+	IsCommentAnonymous bool   `json:"isCommentAnonymous"`
+	IsReviewEnabled    bool   `json:"isReviewEnabled"`
+}
+
+func (u *User) DisplayName(name string) string {
+	if u.Nickname != "" {
+		return u.Nickname
+	}
+	return name
+}
+
+// Review schedules a problem the user missed to be answered again. DueDate
+// and SentDate, the day the review was last sent, are formatted as
+// 2006-01-02.
+type Review struct {
+	ID        string `json:"-"`
+	UserID    string `json:"userID"`
+	ProblemID string `json:"problemID"`
+	Stage     int    `json:"stage"`
+	DueDate   string `json:"dueDate"`
+	SentDate  string `json:"sentDate"`
+	IsDone    bool   `json:"isDone"`
 }
 
 type OMap struct {
@@ -82,46 +170,83 @@ type OMap struct {
 	URLs       []string `json:"urls"`
 }
 
-func (p *Problem) FromRow(header []interface{}, row []interface{}) bool {
+func (p *Problem) HasCorrectOptions() bool {
+	return len(p.CorrectOptions) > 0
+}
+
+func (p *Problem) IsCorrectOption(option int) bool {
+	for _, correctOption := range p.CorrectOptions {
+		if correctOption == option {
+			return true
+		}
+	}
+	return false
+}
+
+// Judge tells whether option is correct, or nil if the problem is a poll.
+func (p *Problem) Judge(option int) *bool {
+	if !p.HasCorrectOptions() {
+		return nil
+	}
+
+	isCorrect := p.IsCorrectOption(option)
+	return &isCorrect
+}
+
+func (p *Problem) FromRow(schema *ProblemSchema, header []interface{}, row []interface{}, imageURL func(imageID string) string) bool {
 	options := []string{}
+	correct := ""
 	for index, value := range row {
-		switch header[index] {
-		case "番号":
+		if index >= len(header) {
+			break
+		}
+
+		name, _ := header[index].(string)
+		if name == "" {
+			continue
+		}
+
+		switch {
+		case name == schema.Index:
 			index, _ := strconv.Atoi(value.(string))
 			p.Index = index
-		case "元画像ID":
+		case name == schema.OriginalImageID:
 			imageID := value.(string)
 			if imageID != "" {
-				p.OriginalImageURL = "https://drive.google.com/uc?export=view&id=" + imageID
+				p.OriginalImageURL = imageURL(imageID)
 			}
-		case "出題画像ID":
+		case name == schema.ProblemImageID:
 			imageID := value.(string)
 			if imageID != "" {
-				p.ProblemImageURL = "https://drive.google.com/uc?export=view&id=" + imageID
+				p.ProblemImageURL = imageURL(imageID)
 			}
-		case "出題文":
+		case name == schema.Text:
 			p.Text = value.(string)
-		case "出題者":
+		case name == schema.Setter:
 			p.Setter = value.(string)
-		case "難易度":
+		case name == schema.Difficulty:
 			difficulty, _ := strconv.Atoi(value.(string))
 			p.Difficulty = difficulty
-		case "選択肢1", "選択肢2", "選択肢3", "選択肢4":
-			if option := value.(string); option != "" {
-				options = append(options, option)
-			}
-		case "解説画像ID":
+		case name == schema.EditorialImageID:
 			imageID := value.(string)
 			if imageID != "" {
-				p.EditorialImageURL = "https://drive.google.com/uc?export=view&id=" + imageID
+				p.EditorialImageURL = imageURL(imageID)
 			}
-		case "解説文":
+		case name == schema.Editorial:
 			p.Editorial = value.(string)
-		case "備考":
+		case name == schema.Note:
 			p.Note = value.(string)
-		case "出題済":
+		case name == schema.ScheduledDate:
+			p.ScheduledDate = normalizeDate(value.(string))
+		case name == schema.Submitted:
 			hasSubmitted, _ := value.(string)
 			p.HasSubmitted = (hasSubmitted == "1")
+		case name == schema.Correct:
+			correct = value.(string)
+		case schema.OptionPrefix != "" && strings.HasPrefix(name, schema.OptionPrefix):
+			if option := value.(string); option != "" {
+				options = append(options, option)
+			}
 		}
 	}
 
@@ -130,23 +255,56 @@ func (p *Problem) FromRow(header []interface{}, row []interface{}) bool {
 	}
 
 	p.Options = append(options, "その他")
+	p.CorrectOptions = parseCorrectOptions(correct, p.Options)
 	return p.OriginalImageURL != ""
 }
 
-func (m *OMap) FromRow(header []interface{}, row []interface{}) bool {
+func parseCorrectOptions(value string, options []string) []int {
+	correctOptions := []int{}
+	for _, field := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '、' || r == '，'
+	}) {
+		field = strings.TrimSpace(field)
+		if number, err := strconv.Atoi(field); err == nil {
+			if number >= 1 && number <= len(options) {
+				correctOptions = append(correctOptions, number-1)
+			}
+			continue
+		}
+
+		for index, option := range options {
+			if option == field {
+				correctOptions = append(correctOptions, index)
+				break
+			}
+		}
+	}
+	return correctOptions
+}
+
+func (m *OMap) FromRow(schema *MapSchema, header []interface{}, row []interface{}) bool {
 	urls := []string{}
 	for index, value := range row {
-		switch header[index] {
-		case "テレイン名":
+		if index >= len(header) {
+			break
+		}
+
+		name, _ := header[index].(string)
+		if name == "" {
+			continue
+		}
+
+		switch {
+		case name == schema.Name:
 			m.Name = value.(string)
-		case "年度":
+		case name == schema.Year:
 			year, _ := strconv.Atoi(value.(string))
 			m.Year = year
-		case "イベント名":
+		case name == schema.Event:
 			m.Event = value.(string)
-		case "競技形式":
+		case name == schema.Regulation:
 			m.Regulation = value.(string)
-		case "URL1", "URL2":
+		case schema.URLPrefix != "" && strings.HasPrefix(name, schema.URLPrefix):
 			if url := value.(string); url != "" {
 				urls = append(urls, url)
 			}
@@ -157,168 +315,60 @@ func (m *OMap) FromRow(header []interface{}, row []interface{}) bool {
 	return m.Name != ""
 }
 
-func (h *AppHandler) readProblems(ctx context.Context) ([]*Problem, error) {
-	b, err := ioutil.ReadFile(consts.GoogleCredentialPath())
-	if err != nil {
-		return []*Problem{}, err
-	}
-
-	credential := map[string]interface{}{}
-	err = json.Unmarshal(b, &credential)
-	if err != nil {
-		return []*Problem{}, err
-	}
-
-	config := &jwt.Config{
-		Email:      credential["client_email"].(string),
-		PrivateKey: []byte(credential["private_key"].(string)),
-		Scopes: []string{
-			"https://www.googleapis.com/auth/drive",
-		},
-		TokenURL: google.JWTTokenURL,
-	}
-
-	sheetService, err := sheets.NewService(ctx, option.WithTokenSource(config.TokenSource(oauth2.NoContext)))
-	if err != nil {
-		return []*Problem{}, err
-	}
-
-	valueRange, err := sheetService.Spreadsheets.Values.Get(consts.SheetID(), "問題!A1:N1000").Do()
-	if err != nil {
-		return []*Problem{}, err
-	}
-
-	header := valueRange.Values[0]
-	problems := []*Problem{}
-	for index, row := range valueRange.Values {
-		if index == 0 {
-			continue
-		}
-
-		problem := new(Problem)
-		if problem.FromRow(header, row) && !problem.HasSubmitted {
-			problems = append(problems, problem)
-		}
-	}
-
-	return problems, nil
-}
-
-func (h *AppHandler) setProblemSubmitted(ctx context.Context, index int) error {
-	b, err := ioutil.ReadFile(consts.GoogleCredentialPath())
-	if err != nil {
-		return err
-	}
-
-	credential := map[string]interface{}{}
-	err = json.Unmarshal(b, &credential)
+func (h *AppHandler) readImageAspectRatio(ctx context.Context, imageURL string) (string, error) {
+	body, err := h.problemSource.OpenImage(ctx, imageURL)
 	if err != nil {
-		return err
-	}
-
-	config := &jwt.Config{
-		Email:      credential["client_email"].(string),
-		PrivateKey: []byte(credential["private_key"].(string)),
-		Scopes: []string{
-			"https://www.googleapis.com/auth/drive",
-		},
-		TokenURL: google.JWTTokenURL,
+		return "", err
 	}
 
-	sheetService, err := sheets.NewService(ctx, option.WithTokenSource(config.TokenSource(oauth2.NoContext)))
+	defer body.Close()
+	config, _, err := image.DecodeConfig(body)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	_, err = sheetService.Spreadsheets.Values.Update(consts.SheetID(), fmt.Sprintf("問題!N%d", index+1), &sheets.ValueRange{
-		Values: [][]interface{}{
-			[]interface{}{
-				"1",
-			},
-		},
-	}).ValueInputOption("USER_ENTERED").Do()
-
-	return err
+	return fmt.Sprintf("%d:%d", config.Width, config.Height), nil
 }
 
-func (h *AppHandler) readOMaps(ctx context.Context) ([]*OMap, error) {
-	b, err := ioutil.ReadFile(consts.GoogleCredentialPath())
-	if err != nil {
-		return []*OMap{}, err
-	}
-
-	credential := map[string]interface{}{}
-	err = json.Unmarshal(b, &credential)
-	if err != nil {
-		return []*OMap{}, err
-	}
-
-	config := &jwt.Config{
-		Email:      credential["client_email"].(string),
-		PrivateKey: []byte(credential["private_key"].(string)),
-		Scopes: []string{
-			"https://www.googleapis.com/auth/drive",
-		},
-		TokenURL: google.JWTTokenURL,
-	}
-
-	sheetService, err := sheets.NewService(ctx, option.WithTokenSource(config.TokenSource(oauth2.NoContext)))
-	if err != nil {
-		return []*OMap{}, err
-	}
-
-	valueRange, err := sheetService.Spreadsheets.Values.Get(consts.SheetID(), "地図!A1:F1000").Do()
-	if err != nil {
-		return []*OMap{}, err
-	}
-
-	header := valueRange.Values[0]
-	maps := []*OMap{}
-	for index, row := range valueRange.Values {
-		if index == 0 {
-			continue
-		}
-
-		omap := new(OMap)
-		if omap.FromRow(header, row) {
-			maps = append(maps, omap)
-		}
-	}
-
-	return maps, nil
-}
+func evaluateTemplate(templateFilePath string, args map[string]interface{}) (string, error) {
+	argsJson, _ := json.Marshal(args)
 
-func (h *AppHandler) readImageAspectRatio(ctx context.Context, imageURL string) (string, error) {
-	response, err := http.Get(imageURL)
+	file, err := os.Open(templateFilePath)
 	if err != nil {
 		return "", err
 	}
+	defer file.Close()
 
-	defer response.Body.Close()
-	config, _, err := image.DecodeConfig(response.Body)
+	b, err := ioutil.ReadAll(file)
 	if err != nil {
 		return "", err
 	}
 
-	return fmt.Sprintf("%d:%d", config.Width, config.Height), nil
+	vm := jsonnet.MakeVM()
+	vm.ExtVar("args", string(argsJson))
+	return vm.EvaluateSnippet(templateFilePath, string(b))
 }
 
-func (h *AppHandler) pushFlexMessage(ctx context.Context, accessToken string, to string, altText string, templateFilePath string, args map[string]interface{}) error {
-	argsJson, _ := json.Marshal(args)
+// maxCarouselBubbles is the most bubbles LINE accepts in a carousel.
+const maxCarouselBubbles = 12
 
-	file, err := os.Open(templateFilePath)
+func (h *AppHandler) replyFlexMessage(bot *linebot.Client, replyToken string, altText string, templateFilePath string, args map[string]interface{}) error {
+	flexJson, err := evaluateTemplate(templateFilePath, args)
 	if err != nil {
 		return err
 	}
 
-	b, err := ioutil.ReadAll(file)
+	contents, err := linebot.UnmarshalFlexMessageJSON([]byte(flexJson))
 	if err != nil {
 		return err
 	}
 
-	vm := jsonnet.MakeVM()
-	vm.ExtVar("args", string(argsJson))
-	flexJson, err := vm.EvaluateSnippet(templateFilePath, string(b))
+	_, err = bot.ReplyMessage(replyToken, linebot.NewFlexMessage(altText, contents)).Do()
+	return err
+}
+
+func (h *AppHandler) pushFlexMessage(ctx context.Context, accessToken string, to string, altText string, templateFilePath string, args map[string]interface{}) error {
+	flexJson, err := evaluateTemplate(templateFilePath, args)
 	if err != nil {
 		return err
 	}
@@ -350,12 +400,12 @@ func (h *AppHandler) pushFlexMessage(ctx context.Context, accessToken string, to
 }
 
 func (h *AppHandler) Webhook(c echo.Context) error {
-	botName := c.Param("botName")
-
-	channelSecret := consts.ChannelSecret(botName)
-	channelAccessToken := consts.ChannelAccessToken(botName)
+	botConfig, ok := h.bots.Get(c.Param("botName"))
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "Bot not found")
+	}
 
-	bot, err := linebot.New(channelSecret, channelAccessToken)
+	bot, err := linebot.New(botConfig.ChannelSecret, botConfig.ChannelAccessToken)
 	if err != nil {
 		return err
 	}
@@ -366,93 +416,50 @@ func (h *AppHandler) Webhook(c echo.Context) error {
 	}
 
 	for _, lineEvent := range lineEvents {
-		// switch lineEvent.Source.Type {
-		// case linebot.EventSourceTypeGroup:
-		// 	log.Printf("%s: %s\n", botName, lineEvent.Source.GroupID)
-		// }
-
 		switch lineEvent.Type {
+		case linebot.EventTypeJoin, linebot.EventTypeLeave, linebot.EventTypeMemberJoined, linebot.EventTypeMemberLeft:
+			err = h.handleGroupEvent(context.Background(), botConfig, lineEvent)
+			if err != nil {
+				log.Printf(`Failed to update group: message %s`, err.Error())
+			}
+
+			if lineEvent.Type == linebot.EventTypeJoin {
+				text := botConfig.DisplayName + "を招待いただきありがとうございます！管理者の承認後に問題の配信を開始します。"
+				if h.isConfigured(eventGroupID(lineEvent)) {
+					text = botConfig.DisplayName + "を招待いただきありがとうございます！問題の配信を開始します。"
+				}
+
+				textMessage := linebot.NewTextMessage(text)
+				_, err = bot.ReplyMessage(lineEvent.ReplyToken, textMessage).Do()
+				if err != nil {
+					log.Printf(`Failed to reply message: message %s`, err.Error())
+				}
+			}
 		case linebot.EventTypeMessage:
 			switch lineMessage := lineEvent.Message.(type) {
 			case *linebot.TextMessage:
-				queries := strings.Split(lineMessage.Text, "　")
-				switch queries[0] {
-				case "設定":
-					textMessage := linebot.NewTextMessage("名前の公開").
-						WithQuickReplies(linebot.NewQuickReplyItems(
-							linebot.NewQuickReplyButton("", linebot.NewMessageAction("する", "名前の公開：オン")),
-							linebot.NewQuickReplyButton("", linebot.NewMessageAction("しない", "名前の公開：オフ")),
-						))
-
-					_, err = bot.ReplyMessage(lineEvent.ReplyToken, textMessage).Do()
-					if err != nil {
-						log.Printf(`Failed to reply message: message %s`, err.Error())
-					}
-				case "名前の公開：オン":
-					_, err = firebase_.Client.Firestore.Doc("users/"+lineEvent.Source.UserID).Set(context.Background(), map[string]interface{}{
-						"isHidden": false,
-					}, firestore.MergeAll)
-
-					if err != nil {
-						log.Printf(`Failed to set isHidden = false: message %s`, err.Error())
-					}
-
-					textMessage := linebot.NewTextMessage("設定を更新しました！")
-					_, err = bot.ReplyMessage(lineEvent.ReplyToken, textMessage).Do()
-					if err != nil {
-						log.Printf(`Failed to reply message: message %s`, err.Error())
-					}
-				case "名前の公開：オフ":
-					_, err = firebase_.Client.Firestore.Doc("users/"+lineEvent.Source.UserID).Set(context.Background(), map[string]interface{}{
-						"isHidden": true,
-					}, firestore.MergeAll)
-
-					if err != nil {
-						log.Printf(`Failed to set isHidden = true: message %s`, err.Error())
-					}
-
-					textMessage := linebot.NewTextMessage("設定を更新しました！")
-					_, err = bot.ReplyMessage(lineEvent.ReplyToken, textMessage).Do()
-					if err != nil {
-						log.Printf(`Failed to reply message: message %s`, err.Error())
-					}
-				// case "問題":
-				// 	err = h.PushProblem(context.Background())
-				// 	if err != nil {
-				// 		log.Printf(`Failed to push problem: message %s`, err.Error())
-				// 	}
-				// case "解説":
-				// 	err = h.PushEditorial(context.Background())
-				// 	if err != nil {
-				// 		log.Printf(`Failed to push editorial: message %s`, err.Error())
-				// 	}
-				case "地図":
-					maps := []*OMap{}
-					for _, omap := range h.maps {
-						ok := (len(queries) == 1)
-						if len(queries) > 1 {
-							for _, q := range queries[1:] {
-								ok = ok || (omap.Regulation == q)
-							}
-						}
-
-						if ok {
-							maps = append(maps, omap)
-						}
-					}
-
-					omap := maps[rand.Intn(len(maps))]
-					lines := append([]string{
-						omap.Name,
-						fmt.Sprintf("%d年度 %s (%s)", omap.Year, omap.Event, omap.Regulation),
-					}, omap.URLs...)
-
-					_, err = bot.ReplyMessage(lineEvent.ReplyToken, linebot.NewTextMessage(strings.Join(lines, "\n"))).Do()
-					if err != nil {
-						log.Printf(`Failed to reply message: message %s`, err.Error())
-					}
+				err = h.commands.Dispatch(&CommandContext{
+					Context: context.Background(),
+					Bot:     botConfig,
+					Client:  bot,
+					Event:   lineEvent,
+				}, lineMessage.Text)
+
+				if err != nil {
+					log.Printf(`Failed to run command: message %s`, err.Error())
 				}
 			}
+		case linebot.EventTypePostback:
+			err = h.dispatchPostback(&CommandContext{
+				Context: context.Background(),
+				Bot:     botConfig,
+				Client:  bot,
+				Event:   lineEvent,
+			}, lineEvent.Postback)
+
+			if err != nil {
+				log.Printf(`Failed to handle postback: message %s`, err.Error())
+			}
 		}
 	}
 
@@ -466,21 +473,16 @@ func (h *AppHandler) LiffIndex(c echo.Context) error {
 func (h *AppHandler) LiffProblem(c echo.Context) error {
 	ctx := context.Background()
 	problemID := c.Param("problemID")
-	problem := h.problems[problemID]
+	problem := h.cachedProblem(problemID)
 
 	if problem == nil {
-		problemSnapshot, err := firebase_.Client.Firestore.Collection("problems").Doc(problemID).Get(ctx)
-		if err != nil {
-			return c.NoContent(http.StatusOK)
-		}
-
-		problem = new(Problem)
-		err = problemSnapshot.DataTo(problem)
+		var err error
+		problem, err = h.problemStore.Get(ctx, problemID)
 		if err != nil {
 			return c.NoContent(http.StatusOK)
 		}
 
-		h.problems[problemID] = problem
+		h.cacheProblem(problem)
 	}
 
 	return c.Render(http.StatusOK, "problem.html", map[string]interface{}{
@@ -492,10 +494,6 @@ func (h *AppHandler) LiffProblem(c echo.Context) error {
 }
 
 func (h *AppHandler) LiffSubmit(c echo.Context) error {
-	if h.todayProblem == nil {
-		return c.NoContent(http.StatusOK)
-	}
-
 	type Parameter struct {
 		ProblemID   string `json:"problemID"`
 		UserID      string `json:"userID"`
@@ -510,59 +508,232 @@ func (h *AppHandler) LiffSubmit(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid parameter")
 	}
 
-	if h.todayProblem.ID != param.ProblemID {
+	round := h.roundOf(param.ProblemID)
+	if round == nil {
 		return c.NoContent(http.StatusOK)
 	}
 
+	if param.Option < 0 || param.Option >= len(round.Problem.Options) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid option")
+	}
+
 	answer := &Answer{
-		ProblemID:    param.ProblemID,
-		UserID:       param.UserID,
-		UserName:     param.UserName,
-		UserGroupID:  param.UserGroupID,
-		UserIsHidden: false,
-		Option:       param.Option,
-		Comment:      param.Comment,
+		ProblemID:   param.ProblemID,
+		UserID:      param.UserID,
+		UserName:    param.UserName,
+		UserGroupID: param.UserGroupID,
+		Option:      param.Option,
+		Comment:     param.Comment,
 	}
 
-	userSnapshot, err := firebase_.Client.Firestore.Doc("users/" + param.UserID).Get(context.Background())
+	_, err := h.submitAnswer(context.Background(), round, answer)
 	if err != nil {
-		_, err := firebase_.Client.Firestore.Doc("users/"+param.UserID).Set(context.Background(), map[string]interface{}{
-			"name":      param.UserName,
-			"groupID":   param.UserGroupID,
-			"createdAt": firestore.ServerTimestamp,
-		}, firestore.MergeAll)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save answer")
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// submitAnswer records answer in round, filling in the user settings. The
+// user is created on the first answer. It reports false when round is
+// already closed.
+func (h *AppHandler) submitAnswer(ctx context.Context, round *Round, answer *Answer) (bool, error) {
+	answer.IsCorrect = round.Problem.Judge(answer.Option)
+
+	user, err := h.userStore.Get(ctx, answer.UserID)
+	if err == ErrNotFound {
+		err := h.userStore.Create(ctx, &User{
+			ID:      answer.UserID,
+			Name:    answer.UserName,
+			GroupID: answer.UserGroupID,
+		})
 
 		if err != nil {
 			log.Printf(`
 				Failed to create user
 					data: %s
 					message %s
-			`, json_.Marshal(param), err.Error())
+			`, json_.Marshal(answer), err.Error())
 		}
+	} else if err != nil {
+		log.Printf(`
+			Failed to get user
+				userID: %s
+				message %s
+		`, answer.UserID, err.Error())
+
+		return false, err
 	} else {
-		user := new(User)
-		err = userSnapshot.DataTo(user)
-		if err == nil {
-			answer.UserIsHidden = user.IsHidden
+		answer.UserName = user.DisplayName(answer.UserName)
+		answer.UserIsHidden = user.IsHidden
+		answer.CommentIsAnonymous = user.IsCommentAnonymous
+
+		if answer.UserGroupID != "" && user.GroupID != answer.UserGroupID {
+			err = h.userStore.Update(ctx, user.ID, map[string]interface{}{"groupID": answer.UserGroupID})
+			if err != nil {
+				log.Printf(`Failed to save user: message %s`, err.Error())
+			}
 		}
 	}
 
-	h.answers[param.UserID] = answer
-	return c.NoContent(http.StatusOK)
+	ok, err := round.Submit(ctx, answer, h.answerStore.Save)
+	if err != nil {
+		log.Printf(`
+			Failed to create answer
+				data: %s
+				message %s
+		`, json_.Marshal(answer), err.Error())
+	}
+
+	return ok, err
+}
+
+func (h *AppHandler) Cohorts() []*Cohort {
+	return h.cohorts
+}
+
+func (h *AppHandler) cohort(name string) (*Cohort, bool) {
+	for _, cohort := range h.cohorts {
+		if cohort.Name == name {
+			return cohort, true
+		}
+	}
+	return nil, false
+}
+
+func (h *AppHandler) currentRound(cohort string) *Round {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return h.rounds[cohort]
+}
+
+// roundOf finds the open round of problemID in any cohort.
+func (h *AppHandler) roundOf(problemID ProblemID) *Round {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for _, round := range h.rounds {
+		if round.Problem.ID == problemID {
+			return round
+		}
+	}
+	return nil
+}
+
+func (h *AppHandler) swapRound(cohort string, round *Round) *Round {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	old := h.rounds[cohort]
+	if round != nil {
+		h.rounds[cohort] = round
+		h.problems[round.Problem.ID] = round.Problem
+	} else {
+		delete(h.rounds, cohort)
+	}
+
+	return old
+}
+
+// UpcomingProblems previews the problems pushed to cohort on each of days.
+func (h *AppHandler) UpcomingProblems(ctx context.Context, cohortName string, days []time.Time) ([]*Problem, error) {
+	cohort, ok := h.cohort(cohortName)
+	if !ok {
+		return []*Problem{}, fmt.Errorf("cohort %s not found", cohortName)
+	}
+
+	problems, err := h.problemSource.ReadProblems(ctx, cohort.Pool)
+	if err != nil {
+		return []*Problem{}, err
+	}
+
+	last, err := h.problemStore.Latest(ctx, cohort.Name)
+	if err != nil && err != ErrNotFound {
+		return []*Problem{}, err
+	}
+
+	return cohort.selector.Queue(problems, last, days), nil
+}
+
+func (h *AppHandler) HasOpenRound(cohort string) bool {
+	return h.currentRound(cohort) != nil
+}
+
+// DiscardRound closes the open problem of cohort without pushing its
+// editorial.
+func (h *AppHandler) DiscardRound(ctx context.Context, cohort string) error {
+	round := h.swapRound(cohort, nil)
+	if round == nil {
+		return nil
+	}
+
+	round.Close()
+	return h.problemStore.Close(ctx, round.Problem.ID, nil)
+}
+
+func (h *AppHandler) cachedProblem(problemID ProblemID) *Problem {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return h.problems[problemID]
+}
+
+func (h *AppHandler) cacheProblem(problem *Problem) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.problems[problem.ID] = problem
+}
+
+func (h *AppHandler) omaps() []*OMap {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return h.maps
+}
+
+func (h *AppHandler) Restore(ctx context.Context) error {
+	for _, cohort := range h.cohorts {
+		problem, err := h.problemStore.Open(ctx, cohort.Name)
+		if err == ErrNotFound {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		answers, err := h.answerStore.ListByProblem(ctx, problem.ID)
+		if err != nil {
+			return err
+		}
+
+		h.swapRound(cohort.Name, NewRound(problem, answers))
+	}
+
+	return nil
 }
 
 func (h *AppHandler) UpdateMaps(ctx context.Context) error {
-	maps, err := h.readOMaps(context.Background())
+	maps, err := h.mapSource.ReadOMaps(ctx)
 	if err != nil {
 		return err
 	}
 
+	h.mutex.Lock()
 	h.maps = maps
+	h.mutex.Unlock()
 	return nil
 }
 
-func (h *AppHandler) PushProblem(ctx context.Context) error {
-	problems, err := h.readProblems(ctx)
+func (h *AppHandler) PushProblem(ctx context.Context, cohortName string) error {
+	cohort, ok := h.cohort(cohortName)
+	if !ok {
+		return fmt.Errorf("cohort %s not found", cohortName)
+	}
+
+	problems, err := h.problemSource.ReadProblems(ctx, cohort.Pool)
 	if err != nil {
 		return err
 	}
@@ -571,11 +742,19 @@ func (h *AppHandler) PushProblem(ctx context.Context) error {
 		return nil
 	}
 
-	problem := problems[rand.Intn(len(problems))]
+	last, err := h.problemStore.Latest(ctx, cohort.Name)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
 
-	data := json_.ToMap(problem)
-	data["createdAt"] = firestore.ServerTimestamp
-	problemRef, _, err := firebase_.Client.Firestore.Collection("problems").Add(ctx, data)
+	problem := cohort.selector.Select(problems, last, time.Now())
+	if problem == nil {
+		return nil
+	}
+
+	problem.Cohort = cohort.Name
+
+	err = h.problemStore.Create(ctx, problem)
 	if err != nil {
 		log.Printf(`
 			Failed to create problem
@@ -586,32 +765,28 @@ func (h *AppHandler) PushProblem(ctx context.Context) error {
 		return err
 	}
 
-	problem.ID = problemRef.ID
-	h.todayProblem = problem
-	h.problems[problem.ID] = problem
-	h.answers = map[string]*Answer{}
+	if old := h.swapRound(cohort.Name, NewRound(problem, []*Answer{})); old != nil {
+		old.Close()
+		err = h.problemStore.Close(ctx, old.Problem.ID, nil)
+		if err != nil {
+			log.Printf(`
+				Failed to close problem
+					problemID: %s
+					message %s
+			`, old.Problem.ID, err.Error())
+		}
+	}
 
 	aspectRatio, err := h.readImageAspectRatio(ctx, problem.OriginalImageURL)
 	if err != nil {
 		aspectRatio = "1:1"
 	}
 
-	botNames := []string{
-		"CHIMPANZEE", "CRAB", "RABBIT", "HAMSTER", "BUFFALO",
-	}
-
-	for _, botName := range botNames {
-		channelAccessToken := consts.ChannelAccessToken(botName)
-		groupID := consts.GroupID(botName)
-
-		if channelAccessToken == "" || groupID == "" {
-			continue
-		}
-
+	for _, target := range h.pushTargets(ctx, cohort.Name) {
 		err = h.pushFlexMessage(
 			ctx,
-			channelAccessToken,
-			groupID,
+			target.Bot.ChannelAccessToken,
+			target.GroupID,
 			"今日の1レッグ",
 			consts.ProblemTemplatePath(),
 			map[string]interface{}{
@@ -621,152 +796,66 @@ func (h *AppHandler) PushProblem(ctx context.Context) error {
 				"text":             problem.Text,
 				"difficulty":       problem.Difficulty,
 				"setter":           problem.Setter,
+				"answerButtons":    answerButtons(problem, target.AnswerButtons),
 			},
 		)
 
 		if err != nil {
 			log.Printf(`
 				Failed to push problem
-					botName: %s
+					bot: %s
+					group: %s (%s)
 					problemID: %d
 					message: %s
-			`, botName, problem.Index, err.Error())
+			`, target.Bot.DisplayName, target.DisplayName, target.GroupID, problem.Index, err.Error())
 		}
 	}
 
-	return h.setProblemSubmitted(ctx, problem.Index)
+	return h.problemSource.SetProblemSubmitted(ctx, problem)
 }
 
-func (h *AppHandler) PushEditorial(ctx context.Context) error {
-	if h.todayProblem == nil {
+func (h *AppHandler) PushEditorial(ctx context.Context, cohort string) error {
+	round := h.swapRound(cohort, nil)
+	if round == nil {
 		return nil
 	}
 
-	type Result struct {
-		Option        string   `json:"option"`
-		Rate          int      `json:"rate"`
-		Count         int      `json:"count"`
-		IsMajority    bool     `json:"isMajority"`
-		Answerers     []string `json:"answerers"`
-		AnswerersText string   `json:"answerersText"`
-	}
-
-	type Comment struct {
-		UserName string `json:"userName"`
-		Text     string `json:"text"`
-	}
-
-	results := []*Result{}
-	commentLists := [][]*Comment{}
-
-	for _, option := range h.todayProblem.Options {
-		results = append(results, &Result{
-			Option: option,
-			Rate:   0,
-			Count:  0,
-		})
-
-		commentLists = append(commentLists, []*Comment{})
-	}
-
-	maxCount := 0
-	for _, answer := range h.answers {
-		result := results[answer.Option]
-
-		count := result.Count + 1
-		result.Count = count
-		if count > maxCount {
-			maxCount = count
-		}
-
-		if !answer.UserIsHidden {
-			result.Answerers = append(result.Answerers, answer.UserName)
-		}
-
-		results[answer.Option] = result
-
-		if answer.Comment != "" {
-			commentLists[answer.Option] = append(commentLists[answer.Option], &Comment{
-				UserName: answer.UserName,
-				Text:     answer.Comment,
-			})
-		}
-
-		data := json_.ToMap(answer)
-		data["createdAt"] = firestore.ServerTimestamp
-		answerRef, _, err := firebase_.Client.Firestore.Collection("answers").Add(context.Background(), data)
-		if err != nil {
-			log.Printf(`
-				Failed to create answer
-					data: %s
-					message %s
-			`, json_.Marshal(answer), err.Error())
-			continue
-		}
-
-		answer.ID = answerRef.ID
-	}
-
-	for _, result := range results {
-		if len(h.answers) > 0 {
-			result.Rate = result.Count * 100 / len(h.answers)
-		}
-		result.IsMajority = (result.Count == maxCount)
-
-		answerers := result.Answerers
-
-		elseCount := result.Count
-		if len(answerers) > 10 {
-			elseCount -= 10
-		} else {
-			elseCount -= len(answerers)
-		}
+	problem := round.Problem
+	answers := round.Close()
 
-		if len(answerers) == 0 {
-			if result.Count > 0 {
-				result.AnswerersText = fmt.Sprintf("回答者%d人", elseCount)
-			} else {
-				result.AnswerersText = "回答者なし"
-			}
-		} else {
-			text := strings.Join(answerers[:result.Count-elseCount], "、")
-			if elseCount > 0 {
-				text = text + fmt.Sprintf(" 他%d人", elseCount)
-			}
-			result.AnswerersText = text
-		}
-	}
-
-	aspectRatio, err := h.readImageAspectRatio(ctx, h.todayProblem.EditorialImageURL)
+	aspectRatio, err := h.readImageAspectRatio(ctx, problem.EditorialImageURL)
 	if err != nil {
 		aspectRatio = "1:1"
 	}
 
-	args := json_.ToMap(map[string]interface{}{
-		"imageURL":         h.todayProblem.EditorialImageURL,
-		"imageAspectRatio": aspectRatio,
-		"text":             h.todayProblem.Editorial,
-		"count":            len(h.answers),
-		"results":          results,
-		"commentLists":     commentLists,
-	})
-
-	botNames := []string{
-		"CHIMPANZEE", "CRAB", "RABBIT", "HAMSTER", "BUFFALO",
-	}
-
-	for _, botName := range botNames {
-		channelAccessToken := consts.ChannelAccessToken(botName)
-		groupID := consts.GroupID(botName)
-
-		if channelAccessToken == "" || groupID == "" {
-			continue
-		}
+	// Each group sees its own answers, with every group of the cohort in
+	// the overall row. Answers sent outside of a group only count there.
+	overallResults, _ := summarizeAnswers(problem, answers)
+	groupAnswers := map[GroupID][]*Answer{}
+	for _, answer := range answers {
+		groupAnswers[answer.UserGroupID] = append(groupAnswers[answer.UserGroupID], answer)
+	}
+
+	for _, target := range h.pushTargets(ctx, cohort) {
+		results, commentLists := summarizeAnswers(problem, groupAnswers[target.GroupID])
+		args := json_.ToMap(map[string]interface{}{
+			"imageURL":         problem.EditorialImageURL,
+			"imageAspectRatio": aspectRatio,
+			"text":             problem.Editorial,
+			"count":            len(groupAnswers[target.GroupID]),
+			"results":          results,
+			"commentLists":     commentLists,
+			"overallCount":     len(answers),
+			"overallResults":   overallResults,
+			"showOverall":      len(answers) > len(groupAnswers[target.GroupID]),
+			"hasCorrect":       problem.HasCorrectOptions(),
+			"correctRate":      correctRate(problem, groupAnswers[target.GroupID]),
+		})
 
 		err = h.pushFlexMessage(
 			ctx,
-			channelAccessToken,
-			groupID,
+			target.Bot.ChannelAccessToken,
+			target.GroupID,
 			"今日の1レッグ（解説）",
 			consts.EditorialTemplatePath(),
 			args,
@@ -775,13 +864,22 @@ func (h *AppHandler) PushEditorial(ctx context.Context) error {
 		if err != nil {
 			log.Printf(`
 				Failed to push editorial
-					botName: %s
+					bot: %s
+					group: %s (%s)
 					problemIndex: %d
 					message: %s
-			`, botName, h.todayProblem.Index, err.Error())
+			`, target.Bot.DisplayName, target.DisplayName, target.GroupID, problem.Index, err.Error())
 		}
 	}
 
-	h.todayProblem = nil
+	err = h.problemStore.Close(ctx, problem.ID, majorityOptions(overallResults))
+	if err != nil {
+		log.Printf(`
+			Failed to close problem
+				problemID: %s
+				message %s
+		`, problem.ID, err.Error())
+	}
+
 	return nil
 }
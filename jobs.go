@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/kuolc/oneLeg/calendar"
+	"github.com/kuolc/oneLeg/scheduler"
+)
+
+// Jobs wraps the handler tasks run by the scheduler with calendar decisions.
+type Jobs struct {
+	handler  *AppHandler
+	calendar *calendar.Calendar
+}
+
+func NewJobs(handler *AppHandler, calendar *calendar.Calendar) *Jobs {
+	return &Jobs{
+		handler:  handler,
+		calendar: calendar,
+	}
+}
+
+func (j *Jobs) UpdateMaps(ctx context.Context) error {
+	return j.handler.UpdateMaps(ctx)
+}
+
+func (j *Jobs) Calibrate(ctx context.Context) error {
+	return j.handler.Calibrate(ctx)
+}
+
+func (j *Jobs) Review(ctx context.Context) error {
+	return j.handler.Review(ctx)
+}
+
+// PushProblem is the push_problem task of cohort.
+func (j *Jobs) PushProblem(cohort string) scheduler.Task {
+	return func(ctx context.Context) error {
+		if blocked, reason := j.calendar.Blocked(time.Now()); blocked {
+			log.Printf("push_problem of %s is suppressed: %s", cohort, reason)
+			return nil
+		}
+
+		if j.calendar.EditorialPolicy() == calendar.EditorialPolicyShift && j.handler.HasOpenRound(cohort) {
+			log.Printf("push_editorial of %s shifted from a blocked day runs before push_problem", cohort)
+
+			err := j.handler.PushEditorial(ctx, cohort)
+			if err != nil {
+				log.Printf(`
+					Failed to push shifted editorial
+						cohort: %s
+						message %s
+				`, cohort, err.Error())
+			}
+		}
+
+		return j.handler.PushProblem(ctx, cohort)
+	}
+}
+
+// PushLeaderboard is the push_<period>_leaderboard task of cohort.
+func (j *Jobs) PushLeaderboard(cohort string, period LeaderboardPeriod) scheduler.Task {
+	return func(ctx context.Context) error {
+		return j.handler.PushLeaderboard(ctx, cohort, period)
+	}
+}
+
+// Remind is the remind task of cohort.
+func (j *Jobs) Remind(cohort string) scheduler.Task {
+	return func(ctx context.Context) error {
+		return j.handler.Remind(ctx, cohort)
+	}
+}
+
+// Upcoming previews the problems pushed to cohort at runs, skipping blocked
+// days.
+func (j *Jobs) Upcoming(ctx context.Context, cohort string, runs []time.Time) ([]time.Time, []*Problem, error) {
+	days := []time.Time{}
+	for _, run := range runs {
+		if blocked, _ := j.calendar.Blocked(run); !blocked {
+			days = append(days, run)
+		}
+	}
+
+	problems, err := j.handler.UpcomingProblems(ctx, cohort, days)
+	if err != nil {
+		return []time.Time{}, []*Problem{}, err
+	}
+
+	return days[:len(problems)], problems, nil
+}
+
+// PushEditorial is the push_editorial task of cohort.
+func (j *Jobs) PushEditorial(cohort string) scheduler.Task {
+	return func(ctx context.Context) error {
+		blocked, reason := j.calendar.Blocked(time.Now())
+		if !blocked {
+			return j.handler.PushEditorial(ctx, cohort)
+		}
+
+		if !j.handler.HasOpenRound(cohort) {
+			log.Printf("push_editorial of %s is suppressed: %s", cohort, reason)
+			return nil
+		}
+
+		switch j.calendar.EditorialPolicy() {
+		case calendar.EditorialPolicyShift:
+			log.Printf("push_editorial of %s is shifted to the next open day: %s", cohort, reason)
+			return nil
+		default:
+			log.Printf("push_editorial of %s is suppressed and the problem is closed: %s", cohort, reason)
+			return j.handler.DiscardRound(ctx, cohort)
+		}
+	}
+}
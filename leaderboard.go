@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/kuolc/oneLeg/consts"
+)
+
+type LeaderboardPeriod = string
+
+const (
+	LeaderboardPeriodWeekly  = "weekly"
+	LeaderboardPeriodMonthly = "monthly"
+)
+
+type LeaderboardEntry struct {
+	Rank        int    `json:"rank"`
+	UserName    string `json:"userName"`
+	Count       int    `json:"count"`
+	Streak      int    `json:"streak"`
+	HasJudged   bool   `json:"hasJudged"`
+	CorrectRate int    `json:"correctRate"`
+}
+
+// computeLeaderboard ranks users by the number of problems answered, then by
+// their streak and accuracy. problems are newest first. Hidden users keep
+// their rank but not their name, as in the editorial. The current settings in
+// users take precedence over the copies on the answers.
+func computeLeaderboard(problems []*Problem, answers map[ProblemID][]*Answer, users map[UserID]*User, limit int) []*LeaderboardEntry {
+	type userScore struct {
+		name         string
+		isHidden     bool
+		count        int
+		streak       int
+		isStreaking  bool
+		judgedCount  int
+		correctCount int
+	}
+
+	scores := map[UserID]*userScore{}
+	for index, problem := range problems {
+		answered := map[UserID]bool{}
+		for _, answer := range answers[problem.ID] {
+			if answered[answer.UserID] {
+				continue
+			}
+			answered[answer.UserID] = true
+
+			score, ok := scores[answer.UserID]
+			if !ok {
+				// The newest answer decides the name and its visibility
+				// unless the user is known.
+				score = &userScore{
+					name:        answer.UserName,
+					isHidden:    answer.UserIsHidden,
+					isStreaking: index == 0,
+				}
+				if user := users[answer.UserID]; user != nil {
+					score.name = user.DisplayName(answer.UserName)
+					score.isHidden = user.IsHidden
+				}
+				scores[answer.UserID] = score
+			}
+
+			score.count++
+			if score.isStreaking {
+				score.streak++
+			}
+
+			if answer.IsCorrect != nil {
+				score.judgedCount++
+				if *answer.IsCorrect {
+					score.correctCount++
+				}
+			}
+		}
+
+		for userID, score := range scores {
+			if !answered[userID] {
+				score.isStreaking = false
+			}
+		}
+	}
+
+	entries := []*LeaderboardEntry{}
+	for _, score := range scores {
+		userName := score.name
+		if score.isHidden {
+			userName = "匿名"
+		}
+
+		entries = append(entries, &LeaderboardEntry{
+			UserName:    userName,
+			Count:       score.count,
+			Streak:      score.streak,
+			HasJudged:   score.judgedCount > 0,
+			CorrectRate: rate(score.correctCount, score.judgedCount),
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		if entries[i].Streak != entries[j].Streak {
+			return entries[i].Streak > entries[j].Streak
+		}
+		if entries[i].CorrectRate != entries[j].CorrectRate {
+			return entries[i].CorrectRate > entries[j].CorrectRate
+		}
+		return entries[i].UserName < entries[j].UserName
+	})
+
+	for index, entry := range entries {
+		entry.Rank = index + 1
+		if index > 0 {
+			previous := entries[index-1]
+			if previous.Count == entry.Count && previous.Streak == entry.Streak && previous.CorrectRate == entry.CorrectRate {
+				entry.Rank = previous.Rank
+			}
+		}
+	}
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	return entries
+}
+
+// leaderboardRange is the days of period the ranking of now covers, from the
+// midnight of the first day to the midnight after the last, in the location of
+// now. A week is the seven days up to and including the day of now. A month is
+// the calendar month of now so far, except on the 1st, when the monthly run
+// ranks the previous month as a whole. Successive runs a period apart neither
+// overlap nor leave gaps.
+func leaderboardRange(period LeaderboardPeriod, now time.Time) (time.Time, time.Time) {
+	end := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	if period == LeaderboardPeriodMonthly {
+		first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		if now.Day() == 1 {
+			return first.AddDate(0, -1, 0), first
+		}
+		return first, end
+	}
+	return end.AddDate(0, 0, -7), end
+}
+
+// PushLeaderboard pushes the ranking of the past week or month to every group
+// of cohort. Each group is ranked by the answers sent from it.
+func (h *AppHandler) PushLeaderboard(ctx context.Context, cohort string, period LeaderboardPeriod) error {
+	from, end := leaderboardRange(period, time.Now().In(h.location))
+	to := end.AddDate(0, 0, -1)
+	title := "週間ランキング"
+	if period == LeaderboardPeriodMonthly {
+		title = "月間ランキング"
+	}
+
+	allProblems, err := h.problemStore.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	problems := []*Problem{}
+	for _, problem := range allProblems {
+		if cohortName(problem.Cohort) == cohort && !problem.CreatedAt.Before(from) && problem.CreatedAt.Before(end) && h.roundOf(problem.ID) == nil {
+			problems = append(problems, problem)
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	groupAnswers := map[GroupID]map[ProblemID][]*Answer{}
+	users := map[UserID]*User{}
+	for _, problem := range problems {
+		answers, err := h.answerStore.ListByProblem(ctx, problem.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, answer := range answers {
+			if _, ok := users[answer.UserID]; !ok {
+				user, err := h.userStore.Get(ctx, answer.UserID)
+				if err != nil && err != ErrNotFound {
+					return err
+				}
+				users[answer.UserID] = user
+			}
+
+			if groupAnswers[answer.UserGroupID] == nil {
+				groupAnswers[answer.UserGroupID] = map[ProblemID][]*Answer{}
+			}
+			groupAnswers[answer.UserGroupID][problem.ID] = append(groupAnswers[answer.UserGroupID][problem.ID], answer)
+		}
+	}
+
+	for _, target := range h.pushTargets(ctx, cohort) {
+		entries := computeLeaderboard(problems, groupAnswers[target.GroupID], users, 10)
+		if len(entries) == 0 {
+			continue
+		}
+
+		err = h.pushFlexMessage(
+			ctx,
+			target.Bot.ChannelAccessToken,
+			target.GroupID,
+			title,
+			consts.LeaderboardTemplatePath(),
+			map[string]interface{}{
+				"title":        title,
+				"period":       fmt.Sprintf("%s〜%s", from.Format("1/2"), to.Format("1/2")),
+				"problemCount": len(problems),
+				"entries":      entries,
+			},
+		)
+
+		if err != nil {
+			log.Printf(`
+				Failed to push leaderboard
+					bot: %s
+					group: %s (%s)
+					message: %s
+			`, target.Bot.DisplayName, target.DisplayName, target.GroupID, err.Error())
+		}
+	}
+
+	return nil
+}
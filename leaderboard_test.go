@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeaderboardRange(t *testing.T) {
+	location := time.FixedZone("JST", 9*60*60)
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, location)
+	}
+
+	tests := []struct {
+		period LeaderboardPeriod
+		now    time.Time
+		from   time.Time
+		end    time.Time
+	}{
+		{LeaderboardPeriodWeekly, time.Date(2020, 3, 8, 20, 0, 0, 0, location), date(2020, 3, 2), date(2020, 3, 9)},
+		{LeaderboardPeriodMonthly, time.Date(2020, 3, 1, 20, 0, 0, 0, location), date(2020, 2, 1), date(2020, 3, 1)},
+		{LeaderboardPeriodMonthly, time.Date(2020, 1, 1, 20, 0, 0, 0, location), date(2019, 12, 1), date(2020, 1, 1)},
+		{LeaderboardPeriodMonthly, time.Date(2020, 3, 15, 20, 0, 0, 0, location), date(2020, 3, 1), date(2020, 3, 16)},
+	}
+
+	for _, test := range tests {
+		from, end := leaderboardRange(test.period, test.now)
+		if !from.Equal(test.from) || !end.Equal(test.end) {
+			t.Errorf("leaderboardRange(%s, %s) = %s, %s, want %s, %s", test.period, test.now, from, end, test.from, test.end)
+		}
+	}
+}
@@ -2,13 +2,15 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"html/template"
 	"io"
 	"log"
-	"time"
 
-	"github.com/kawasin73/htask/cron"
+	"github.com/kuolc/oneLeg/calendar"
 	"github.com/kuolc/oneLeg/consts"
+	"github.com/kuolc/oneLeg/firebase_"
+	"github.com/kuolc/oneLeg/google_"
 	"github.com/kuolc/oneLeg/scheduler"
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
@@ -23,59 +25,140 @@ func (t *Template) Render(w io.Writer, name string, data interface{}, c echo.Con
 }
 
 func main() {
-	h := &AppHandler{
-		problems: make(map[ProblemID]*Problem),
-		answers:  make(map[UserID]*Answer),
-	}
-
-	scheduler.Set("update_maps", func(cr *cron.Cron) *scheduler.Job {
-		cancel, _ := cr.Every(1).Day().At(consts.UpdateMapsAt()).Run(func() {
-			err := h.UpdateMaps(context.Background())
-			if err != nil {
-				log.Printf(`
-					Failed to update maps
-						message %s
-				`, err.Error())
-			}
-		})
-
-		return &scheduler.Job{Cancel: cancel}
-	})
+	schema, err := LoadSchema(consts.SchemaConfigPath())
+	if err != nil {
+		log.Fatalln(err)
+	}
 
-	scheduler.Set("push_problem", func(cr *cron.Cron) *scheduler.Job {
-		cancel, _ := cr.Every(1).Day().At(consts.PushProblemAt()).Run(func() {
-			weekday := time.Now().Weekday()
-			if !(weekday >= 1 && weekday <= 5) {
-				return
-			}
-
-			err := h.PushProblem(context.Background())
-			if err != nil {
-				log.Printf(`
-					Failed to push problem
-						message %s
-				`, err.Error())
-			}
-		})
-
-		return &scheduler.Job{Cancel: cancel}
-	})
+	var problemSource ProblemSource
+	var mapSource MapSource
+	var imageDir string
+	switch consts.Source() {
+	case consts.SourceBackendLocal:
+		source := NewLocalSource(consts.LocalSourceDir(), consts.BaseURL(), schema)
+		problemSource, mapSource, imageDir = source, source, source.ImageDir()
+	default:
+		client, err := google_.NewClient(context.Background(), consts.GoogleCredentialPath(), consts.GoogleTimeout())
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		source := NewSheetsSource(client, consts.SheetID(), schema)
+		problemSource, mapSource = source, source
+	}
+
+	var stores *Stores
+	switch consts.Store() {
+	case consts.StoreBackendMemory:
+		stores = NewMemoryStores()
+	default:
+		client, err := firebase_.NewClient(context.Background())
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer client.Close()
+
+		stores = NewFirestoreStores(client.Firestore)
+	}
 
-	scheduler.Set("push_editorial", func(cr *cron.Cron) *scheduler.Job {
-		cancel, _ := cr.Every(1).Day().At(consts.PushEditorialAt()).Run(func() {
-			err := h.PushEditorial(context.Background())
-			if err != nil {
-				log.Printf(`
-					Failed to push editorial
-						message %s
-				`, err.Error())
-			}
-		})
-
-		return &scheduler.Job{Cancel: cancel}
+	scheduleConfig, err := scheduler.LoadConfig(consts.ScheduleConfigPath(), &scheduler.Config{
+		TimeZone: consts.TimeZone(),
+		Jobs: map[string]*scheduler.JobConfig{
+			"update_maps":    {Spec: consts.UpdateMapsSpec()},
+			"calibrate":      {Spec: consts.CalibrateSpec()},
+			"review":         {Spec: consts.ReviewSpec()},
+			"push_problem":   {Spec: consts.PushProblemSpec(), CatchUp: consts.PushProblemCatchUp()},
+			"push_editorial": {Spec: consts.PushEditorialSpec(), CatchUp: consts.PushEditorialCatchUp()},
+			"remind":         {Spec: consts.RemindSpec()},
+
+			"push_weekly_leaderboard":  {Spec: consts.PushWeeklyLeaderboardSpec()},
+			"push_monthly_leaderboard": {Spec: consts.PushMonthlyLeaderboardSpec()},
+		},
 	})
 
-	err := h.UpdateMaps(context.Background())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	location, err := scheduleConfig.Location()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	selectionConfig, err := LoadSelectionConfig(consts.SelectionConfigPath())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	cohorts, err := LoadCohorts(consts.CohortsConfigPath(), selectionConfig, location)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	bots, err := LoadBotRegistry(consts.BotsConfigPath())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	h := NewAppHandler(problemSource, mapSource, stores, cohorts, bots, location)
+
+	calendarConfig, err := calendar.LoadConfig(consts.CalendarConfigPath())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	cal, err := calendar.New(calendarConfig, location)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	sch := scheduler.New(location, consts.SchedulerStatePath())
+	jobs := NewJobs(h, cal)
+	tasks := map[string]scheduler.Task{
+		"update_maps": jobs.UpdateMaps,
+		"calibrate":   jobs.Calibrate,
+		"review":      jobs.Review,
+	}
+	jobConfigs := map[string]*scheduler.JobConfig{
+		"update_maps": scheduleConfig.Jobs["update_maps"],
+		"calibrate":   scheduleConfig.Jobs["calibrate"],
+		"review":      scheduleConfig.Jobs["review"],
+	}
+
+	for _, cohort := range cohorts {
+		for job, task := range map[string]scheduler.Task{
+			"push_problem":             jobs.PushProblem(cohort.Name),
+			"push_editorial":           jobs.PushEditorial(cohort.Name),
+			"remind":                   jobs.Remind(cohort.Name),
+			"push_weekly_leaderboard":  jobs.PushLeaderboard(cohort.Name, LeaderboardPeriodWeekly),
+			"push_monthly_leaderboard": jobs.PushLeaderboard(cohort.Name, LeaderboardPeriodMonthly),
+		} {
+			tasks[cohort.JobName(job)] = task
+			jobConfigs[cohort.JobName(job)] = cohort.JobConfig(job, scheduleConfig.Jobs[job])
+		}
+	}
+
+	for name, task := range tasks {
+		jobConfig := jobConfigs[name]
+		if !jobConfig.IsEnabled() {
+			log.Printf("%s is disabled", name)
+			continue
+		}
+
+		spec, err := scheduler.Parse(jobConfig.Spec)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		catchUp, err := jobConfig.CatchUpWindow()
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		sch.Register(name, spec, catchUp, task)
+	}
+
+	err = h.UpdateMaps(context.Background())
 	if err != nil {
 		log.Printf(`
 			Failed to update maps
@@ -83,6 +166,16 @@ func main() {
 		`, err.Error())
 	}
 
+	err = h.Restore(context.Background())
+	if err != nil {
+		log.Printf(`
+			Failed to restore today's problem
+				message %s
+		`, err.Error())
+	}
+
+	sch.Start()
+
 	e := echo.New()
 
 	e.Renderer = &Template{
@@ -95,6 +188,26 @@ func main() {
 	e.GET("/liff/problems/:problemID", h.LiffProblem)
 	e.POST("/liff", h.LiffSubmit)
 
+	if imageDir != "" {
+		e.Static("/images", imageDir)
+	}
+
+	if consts.AdminToken() != "" {
+		admin := NewAdminHandler(sch, jobs, h)
+		g := e.Group("/admin", middleware.KeyAuth(func(key string, c echo.Context) (bool, error) {
+			return subtle.ConstantTimeCompare([]byte(key), []byte(consts.AdminToken())) == 1, nil
+		}))
+
+		g.GET("/jobs", admin.ListJobs)
+		g.POST("/jobs/:name/trigger", admin.TriggerJob)
+		g.POST("/jobs/:name/pause", admin.PauseJob)
+		g.POST("/jobs/:name/resume", admin.ResumeJob)
+		g.GET("/problems/upcoming", admin.UpcomingProblems)
+		g.GET("/groups", admin.ListGroups)
+		g.POST("/groups/:groupID/approve", admin.ApproveGroup)
+		g.POST("/groups/:groupID/deactivate", admin.DeactivateGroup)
+	}
+
 	e.HTTPErrorHandler = func(err error, c echo.Context) {
 		e.DefaultHTTPErrorHandler(err, c)
 		print(err.Error())
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MapQuery filters maps. Fields left empty match every map.
+type MapQuery struct {
+	FromYear    int
+	ToYear      int
+	Regulations []string
+	Events      []string
+	Names       []string
+	// Keywords match either the name or the event.
+	Keywords []string
+	IsList   bool
+}
+
+var yearRangePattern = regexp.MustCompile(`^(\d{4})?年?([-~〜])?(\d{4})?年?$`)
+
+// normalizeWidth turns full-width ASCII into half-width, so that ２０１８ and
+// 2018 mean the same.
+func normalizeWidth(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '！' && r <= '～' {
+			return r - '！' + '!'
+		}
+		return r
+	}, s)
+}
+
+func parseYearRange(s string) (int, int, bool) {
+	match := yearRangePattern.FindStringSubmatch(s)
+	if match == nil || (match[1] == "" && match[3] == "") {
+		return 0, 0, false
+	}
+
+	from, _ := strconv.Atoi(match[1])
+	to, _ := strconv.Atoi(match[3])
+	if match[2] == "" {
+		to = from
+	}
+	return from, to, true
+}
+
+// ParseMapQuery reads words such as 2015-2020, ロング, 大会=インカレ or 一覧.
+// A bare word is a year range, a regulation when some map has it, or else a
+// keyword searched in names and events.
+func ParseMapQuery(words []string, maps []*OMap) *MapQuery {
+	regulations := map[string]bool{}
+	for _, omap := range maps {
+		regulations[omap.Regulation] = true
+	}
+
+	query := &MapQuery{}
+	for _, word := range words {
+		word = normalizeWidth(word)
+
+		if index := strings.Index(word, "="); index > 0 {
+			key, value := word[:index], word[index+1:]
+			switch key {
+			case "年", "年度", "year":
+				if from, to, ok := parseYearRange(value); ok {
+					query.FromYear, query.ToYear = from, to
+				}
+			case "大会", "イベント", "event":
+				query.Events = append(query.Events, value)
+			case "名前", "テレイン", "name":
+				query.Names = append(query.Names, value)
+			case "形式", "競技形式", "regulation":
+				query.Regulations = append(query.Regulations, value)
+			}
+			continue
+		}
+
+		if word == "一覧" || strings.EqualFold(word, "list") {
+			query.IsList = true
+			continue
+		}
+
+		if from, to, ok := parseYearRange(word); ok {
+			query.FromYear, query.ToYear = from, to
+			continue
+		}
+
+		if regulations[word] {
+			query.Regulations = append(query.Regulations, word)
+			continue
+		}
+
+		query.Keywords = append(query.Keywords, word)
+	}
+
+	return query
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(s, substring) {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *MapQuery) Match(omap *OMap) bool {
+	if q.FromYear > 0 && omap.Year < q.FromYear {
+		return false
+	}
+
+	if q.ToYear > 0 && omap.Year > q.ToYear {
+		return false
+	}
+
+	if len(q.Regulations) > 0 {
+		ok := false
+		for _, regulation := range q.Regulations {
+			ok = ok || omap.Regulation == regulation
+		}
+
+		if !ok {
+			return false
+		}
+	}
+
+	if len(q.Events) > 0 && !containsAny(omap.Event, q.Events) {
+		return false
+	}
+
+	if len(q.Names) > 0 && !containsAny(omap.Name, q.Names) {
+		return false
+	}
+
+	for _, keyword := range q.Keywords {
+		if !strings.Contains(omap.Name, keyword) && !strings.Contains(omap.Event, keyword) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SearchMaps returns the maps matching q, newest first.
+func SearchMaps(maps []*OMap, q *MapQuery) []*OMap {
+	matches := []*OMap{}
+	for _, omap := range maps {
+		if q.Match(omap) {
+			matches = append(matches, omap)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Year > matches[j].Year
+	})
+
+	return matches
+}
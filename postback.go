@@ -0,0 +1,251 @@
+package main
+
+import (
+	"log"
+	"net/url"
+	"strconv"
+
+	"github.com/kuolc/oneLeg/consts"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+// Postback data is a URL query with the action in "action", such as
+// action=settings&key=isHidden&value=on.
+type PostbackHandler func(c *CommandContext, data url.Values) error
+
+func postbackData(action string, values map[string]string) string {
+	data := url.Values{}
+	data.Set("action", action)
+	for key, value := range values {
+		data.Set(key, value)
+	}
+	return data.Encode()
+}
+
+func (h *AppHandler) newPostbackHandlers() map[string]PostbackHandler {
+	return map[string]PostbackHandler{
+		"settings": h.postbackSettings,
+		"answer":   h.postbackAnswer,
+		"archive":  h.postbackArchive,
+		"practice": h.postbackPractice,
+		"review":   h.postbackReview,
+	}
+}
+
+func (h *AppHandler) dispatchPostback(c *CommandContext, postback *linebot.Postback) error {
+	if postback == nil {
+		return nil
+	}
+
+	data, err := url.ParseQuery(postback.Data)
+	if err != nil {
+		return err
+	}
+
+	handler, ok := h.postbacks[data.Get("action")]
+	if !ok {
+		return nil
+	}
+
+	return handler(c, data)
+}
+
+// userSetting is a flag of User named by its JSON field in Key. Inverted
+// flags are off when the setting is on.
+type userSetting struct {
+	Key        string
+	Label      string
+	IsInverted bool
+}
+
+var userSettings = []*userSetting{
+	{
+		Key:        "isHidden",
+		Label:      "名前の公開",
+		IsInverted: true,
+	},
+	{
+		Key:   "isReminderEnabled",
+		Label: "DMリマインダー",
+	},
+	{
+		Key:   "isCommentAnonymous",
+		Label: "匿名コメント",
+	},
+	{
+		Key:   "isReviewEnabled",
+		Label: "復習",
+	},
+}
+
+// replySettings shows the settings of the user in a 1:1 chat only, as every
+// member of a group could press the buttons showing someone else's state.
+func (h *AppHandler) replySettings(c *CommandContext) error {
+	if !c.IsDirect() {
+		return c.ReplyText("設定はボットとの個別チャットで「設定」と送信して変更してください。")
+	}
+
+	user, err := h.userStore.Get(c, c.Event.Source.UserID)
+	if err == ErrNotFound {
+		user = &User{ID: c.Event.Source.UserID}
+	} else if err != nil {
+		return err
+	}
+
+	toggle := func(key string, isOn bool) map[string]interface{} {
+		return map[string]interface{}{
+			"isOn": isOn,
+			"on":   postbackData("settings", map[string]string{"key": key, "value": "on"}),
+			"off":  postbackData("settings", map[string]string{"key": key, "value": "off"}),
+		}
+	}
+
+	return h.replyFlexMessage(c.Client, c.Event.ReplyToken, "設定", consts.SettingsTemplatePath(), map[string]interface{}{
+		"nickname":           user.Nickname,
+		"nicknameData":       postbackData("settings", map[string]string{"key": "nickname"}),
+		"isHidden":           toggle("isHidden", !user.IsHidden),
+		"isReminderEnabled":  toggle("isReminderEnabled", user.IsReminderEnabled),
+		"isCommentAnonymous": toggle("isCommentAnonymous", user.IsCommentAnonymous),
+		"isReviewEnabled":    toggle("isReviewEnabled", user.IsReviewEnabled),
+	})
+}
+
+func (h *AppHandler) postbackSettings(c *CommandContext, data url.Values) error {
+	key := data.Get("key")
+	if key == "nickname" {
+		return c.ReplyText("「ニックネーム 名前」と送信すると、回答者名がその名前になります。\n「ニックネーム 解除」でLINEの表示名に戻ります。")
+	}
+
+	for _, setting := range userSettings {
+		if setting.Key != key {
+			continue
+		}
+
+		isOn := data.Get("value") == "on"
+		err := h.userStore.Update(c, c.Event.Source.UserID, map[string]interface{}{
+			setting.Key: isOn != setting.IsInverted,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		state := "オフ"
+		if isOn {
+			state = "オン"
+		}
+		return c.ReplyText(setting.Label + "を" + state + "にしました！")
+	}
+
+	return nil
+}
+
+// maxButtonLabelLength is the longest label LINE accepts for an action.
+const maxButtonLabelLength = 20
+
+func buttonLabel(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maxButtonLabelLength {
+		return text
+	}
+	return string(runes[:maxButtonLabelLength-1]) + "…"
+}
+
+// answerButtons lists the postback buttons of problem, or nothing when the
+// target answers through LIFF only.
+func answerButtons(problem *Problem, isEnabled bool) []map[string]string {
+	buttons := []map[string]string{}
+	if !isEnabled {
+		return buttons
+	}
+
+	for i, option := range problem.Options {
+		buttons = append(buttons, map[string]string{
+			"label": buttonLabel(option),
+			"data": postbackData("answer", map[string]string{
+				"problemID": problem.ID,
+				"option":    strconv.Itoa(i),
+			}),
+		})
+	}
+
+	return buttons
+}
+
+// postbackAnswer records an answer from the buttons of the problem. The
+// confirmation goes to the user only, so that the group is neither flooded
+// with replies nor told who has answered.
+func (h *AppHandler) postbackAnswer(c *CommandContext, data url.Values) error {
+	userID := c.Event.Source.UserID
+	if userID == "" {
+		return nil
+	}
+
+	round := h.roundOf(data.Get("problemID"))
+	if round == nil {
+		return h.confirmPrivately(c, "この問題の回答は締め切られました。")
+	}
+
+	option, err := strconv.Atoi(data.Get("option"))
+	if err != nil || option < 0 || option >= len(round.Problem.Options) {
+		return nil
+	}
+
+	answer := &Answer{
+		ProblemID:   round.Problem.ID,
+		UserID:      userID,
+		UserName:    h.profileName(c),
+		UserGroupID: c.Event.Source.GroupID,
+		Option:      option,
+	}
+
+	ok, err := h.submitAnswer(c, round, answer)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return h.confirmPrivately(c, "この問題の回答は締め切られました。")
+	}
+
+	return h.confirmPrivately(c, "「"+round.Problem.Options[option]+"」で回答しました！")
+}
+
+// confirmPrivately replies in a 1:1 chat and pushes to the user from a group.
+// A failed push, as when the user has not added the bot, is only logged and
+// nothing is sent to the group.
+func (h *AppHandler) confirmPrivately(c *CommandContext, text string) error {
+	if c.IsDirect() {
+		return c.ReplyText(text)
+	}
+
+	_, err := c.Client.PushMessage(c.Event.Source.UserID, linebot.NewTextMessage(text)).WithContext(c).Do()
+	if err != nil {
+		log.Printf(`
+			Failed to push confirmation
+				userID: %s
+				message %s
+		`, c.Event.Source.UserID, err.Error())
+	}
+
+	return nil
+}
+
+func (h *AppHandler) profileName(c *CommandContext) string {
+	source := c.Event.Source
+
+	var profile *linebot.UserProfileResponse
+	var err error
+	if source.GroupID != "" {
+		profile, err = c.Client.GetGroupMemberProfile(source.GroupID, source.UserID).WithContext(c).Do()
+	} else {
+		profile, err = c.Client.GetProfile(source.UserID).WithContext(c).Do()
+	}
+
+	if err != nil {
+		log.Printf(`Failed to get profile: message %s`, err.Error())
+		return ""
+	}
+
+	return profile.DisplayName
+}
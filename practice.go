@@ -0,0 +1,124 @@
+package main
+
+import (
+	"math/rand"
+	"net/url"
+	"strconv"
+
+	"github.com/kuolc/oneLeg/consts"
+)
+
+// practiceProblems lists the closed problems of the user's cohort that the
+// user has answered neither in a group nor in practice.
+func (h *AppHandler) practiceProblems(c *CommandContext, userID UserID) ([]*Problem, error) {
+	cohort := DefaultCohortName
+	if user, err := h.userStore.Get(c, userID); err == nil {
+		cohort = h.cohortOf(c, user.GroupID)
+	}
+
+	problems, err := h.archivedProblems(c, cohort)
+	if err != nil {
+		return []*Problem{}, err
+	}
+
+	answered := map[ProblemID]bool{}
+	for _, store := range []AnswerStore{h.answerStore, h.practiceStore} {
+		answers, err := store.ListByUser(c, userID)
+		if err != nil {
+			return []*Problem{}, err
+		}
+
+		for _, answer := range answers {
+			answered[answer.ProblemID] = true
+		}
+	}
+
+	unanswered := []*Problem{}
+	for _, problem := range problems {
+		if !answered[problem.ID] && len(problem.Options) > 0 {
+			unanswered = append(unanswered, problem)
+		}
+	}
+
+	return unanswered, nil
+}
+
+func (h *AppHandler) commandPractice(c *CommandContext) error {
+	problems, err := h.practiceProblems(c, c.Event.Source.UserID)
+	if err != nil {
+		return err
+	}
+
+	if len(problems) == 0 {
+		return c.ReplyText("練習できる過去問はもうありません。")
+	}
+
+	problem := problems[rand.Intn(len(problems))]
+
+	return h.replyFlexMessage(c.Client, c.Event.ReplyToken, "練習問題", consts.PracticeTemplatePath(), practiceArgs(problem, "練習問題", "practice"))
+}
+
+// practiceArgs lays out problem with an answer button per option, each
+// posting back action.
+func practiceArgs(problem *Problem, title string, action string) map[string]interface{} {
+	options := []map[string]string{}
+	for i, option := range problem.Options {
+		options = append(options, map[string]string{
+			"label": buttonLabel(option),
+			"data": postbackData(action, map[string]string{
+				"problemID": problem.ID,
+				"option":    strconv.Itoa(i),
+			}),
+		})
+	}
+
+	return map[string]interface{}{
+		"title":      title,
+		"imageURL":   problem.ProblemImageURL,
+		"text":       problem.Text,
+		"difficulty": problem.Difficulty,
+		"setter":     problem.Setter,
+		"options":    options,
+	}
+}
+
+// postbackPractice records a practice answer and replies with the editorial
+// and the distribution of the daily answers at once.
+func (h *AppHandler) postbackPractice(c *CommandContext, data url.Values) error {
+	problemID := data.Get("problemID")
+	if h.roundOf(problemID) != nil {
+		return nil
+	}
+
+	problem, err := h.problemStore.Get(c, problemID)
+	if err != nil {
+		return err
+	}
+
+	option, err := strconv.Atoi(data.Get("option"))
+	if err != nil || option < 0 || option >= len(problem.Options) {
+		return nil
+	}
+
+	err = h.practiceStore.Save(c, &Answer{
+		ProblemID: problem.ID,
+		UserID:    c.Event.Source.UserID,
+		Option:    option,
+		IsCorrect: problem.Judge(option),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	feedback := "あなたの回答：" + problem.Options[option]
+	if isCorrect := problem.Judge(option); isCorrect != nil {
+		if *isCorrect {
+			feedback += "（正解）"
+		} else {
+			feedback += "（不正解）"
+		}
+	}
+
+	return h.replyArchiveProblem(c, problem.ID, feedback)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+// Remind sends a direct message to each user of cohort who turned reminders
+// on and has not answered the open problem yet.
+func (h *AppHandler) Remind(ctx context.Context, cohort string) error {
+	round := h.currentRound(cohort)
+	if round == nil {
+		return nil
+	}
+
+	users, err := h.userStore.ListReminded(ctx)
+	if err != nil {
+		return err
+	}
+
+	targets := map[GroupID]*PushTarget{}
+	for _, target := range h.pushTargets(ctx, cohort) {
+		targets[target.GroupID] = target
+	}
+
+	for _, user := range users {
+		target, ok := targets[user.GroupID]
+		if !ok || round.HasAnswered(user.ID) {
+			continue
+		}
+
+		bot, err := linebot.New(target.Bot.ChannelSecret, target.Bot.ChannelAccessToken)
+		if err != nil {
+			return err
+		}
+
+		_, err = bot.PushMessage(user.ID, linebot.NewTextMessage("今日の問題にまだ回答していません！\n解説の前に回答してみましょう。")).WithContext(ctx).Do()
+		if err != nil {
+			log.Printf(`
+				Failed to push reminder
+					user: %s
+					message %s
+			`, user.ID, err.Error())
+		}
+	}
+
+	return nil
+}
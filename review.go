@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/kuolc/oneLeg/consts"
+)
+
+// reviewIntervals are the days until the next review at each stage. A review
+// answered right moves on to the next stage, and one answered wrong starts
+// over.
+var reviewIntervals = []int{1, 3, 7, 14, 30}
+
+const reviewDateLayout = "2006-01-02"
+
+// reviewToday is the midnight starting the current day in location, so that
+// due dates follow the calendar of the schedule rather than of the host.
+func reviewToday(location *time.Location) time.Time {
+	now := time.Now().In(location)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+}
+
+func reviewID(userID UserID, problemID ProblemID) string {
+	return userID + "_" + problemID
+}
+
+func newReview(userID UserID, problemID ProblemID, today time.Time) *Review {
+	return &Review{
+		UserID:    userID,
+		ProblemID: problemID,
+		Stage:     0,
+		DueDate:   today.AddDate(0, 0, reviewIntervals[0]).Format(reviewDateLayout),
+	}
+}
+
+// advanceReview schedules review by the answer given on today.
+func advanceReview(review *Review, isAgreed bool, today time.Time) {
+	if isAgreed {
+		review.Stage++
+	} else {
+		review.Stage = 0
+	}
+
+	if review.Stage >= len(reviewIntervals) {
+		review.IsDone = true
+		return
+	}
+
+	review.DueDate = today.AddDate(0, 0, reviewIntervals[review.Stage]).Format(reviewDateLayout)
+}
+
+// missedProblems lists the problems answered against the correct options or,
+// for polls, against the majority. Problems not in problems are skipped.
+func missedProblems(problems map[ProblemID]*Problem, answers []*Answer) []ProblemID {
+	missed := []ProblemID{}
+	for _, answer := range answers {
+		problem, ok := problems[answer.ProblemID]
+		if !ok {
+			continue
+		}
+
+		if agreed, ok := isAgreed(problem, answer); ok && !agreed {
+			missed = append(missed, problem.ID)
+		}
+	}
+	return missed
+}
+
+// dueReview picks the review overdue the longest, if any. A review already
+// sent since it fell due waits for its answer rather than being sent again.
+func dueReview(reviews []*Review, today time.Time) *Review {
+	date := today.Format(reviewDateLayout)
+
+	var due *Review
+	for _, review := range reviews {
+		if review.IsDone || review.DueDate > date || review.SentDate >= review.DueDate {
+			continue
+		}
+
+		if due == nil || review.DueDate < due.DueDate {
+			due = review
+		}
+	}
+	return due
+}
+
+// Review schedules the problems each reviewing user missed and sends one due
+// review to the user directly.
+func (h *AppHandler) Review(ctx context.Context) error {
+	allProblems, err := h.problemStore.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	problems := map[ProblemID]*Problem{}
+	for _, problem := range allProblems {
+		if h.roundOf(problem.ID) == nil {
+			problems[problem.ID] = problem
+		}
+	}
+
+	users, err := h.userStore.ListReviewing(ctx)
+	if err != nil {
+		return err
+	}
+
+	today := reviewToday(h.location)
+	for _, user := range users {
+		err = h.review(ctx, user, problems, today)
+		if err != nil {
+			log.Printf(`
+				Failed to review
+					user: %s
+					message %s
+			`, user.ID, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (h *AppHandler) review(ctx context.Context, user *User, problems map[ProblemID]*Problem, today time.Time) error {
+	reviews, err := h.reviewStore.ListByUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	scheduled := map[ProblemID]bool{}
+	for _, review := range reviews {
+		scheduled[review.ProblemID] = true
+	}
+
+	answers, err := h.answerStore.ListByUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, problemID := range missedProblems(problems, answers) {
+		if scheduled[problemID] {
+			continue
+		}
+
+		review := newReview(user.ID, problemID, today)
+		err = h.reviewStore.Save(ctx, review)
+		if err != nil {
+			return err
+		}
+
+		scheduled[problemID] = true
+		reviews = append(reviews, review)
+	}
+
+	due := dueReview(reviews, today)
+	if due == nil {
+		return nil
+	}
+
+	bot, ok := h.botOf(ctx, user.GroupID)
+	if !ok {
+		return nil
+	}
+
+	problem := problems[due.ProblemID]
+	if problem == nil {
+		return nil
+	}
+
+	err = h.pushFlexMessage(ctx, bot.ChannelAccessToken, user.ID, "復習問題", consts.PracticeTemplatePath(), practiceArgs(problem, "復習問題", "review"))
+	if err != nil {
+		return err
+	}
+
+	due.SentDate = today.Format(reviewDateLayout)
+	return h.reviewStore.Save(ctx, due)
+}
+
+// postbackReview records the answer to a review and replies with the
+// editorial and when the problem comes back.
+func (h *AppHandler) postbackReview(c *CommandContext, data url.Values) error {
+	userID := c.Event.Source.UserID
+	problemID := data.Get("problemID")
+
+	review, err := h.reviewStore.Get(c, reviewID(userID, problemID))
+	if err == ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	today := reviewToday(h.location)
+	if review.IsDone {
+		return c.ReplyText("この問題の復習は完了しています。")
+	}
+	if review.DueDate > today.Format(reviewDateLayout) {
+		return c.ReplyText("この問題の次の復習は" + review.DueDate + "です。")
+	}
+
+	problem, err := h.problemStore.Get(c, problemID)
+	if err != nil {
+		return err
+	}
+
+	option, err := strconv.Atoi(data.Get("option"))
+	if err != nil || option < 0 || option >= len(problem.Options) {
+		return nil
+	}
+
+	answer := &Answer{
+		ProblemID: problem.ID,
+		UserID:    userID,
+		Option:    option,
+		IsCorrect: problem.Judge(option),
+	}
+
+	err = h.practiceStore.Save(c, answer)
+	if err != nil {
+		return err
+	}
+
+	agreed, _ := isAgreed(problem, answer)
+	advanceReview(review, agreed, today)
+
+	err = h.reviewStore.Save(c, review)
+	if err != nil {
+		return err
+	}
+
+	feedback := "あなたの回答：" + problem.Options[option]
+	switch {
+	case problem.HasCorrectOptions() && agreed:
+		feedback += "（正解）"
+	case problem.HasCorrectOptions():
+		feedback += "（不正解）"
+	case agreed:
+		feedback += "（多数派）"
+	default:
+		feedback += "（少数派）"
+	}
+
+	if review.IsDone {
+		feedback += "\nこの問題の復習は完了です！"
+	} else {
+		feedback += fmt.Sprintf("\n次の復習は%d日後です。", reviewIntervals[review.Stage])
+	}
+
+	return h.replyArchiveProblem(c, problem.ID, feedback)
+}
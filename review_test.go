@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDueReview(t *testing.T) {
+	today := time.Date(2020, 1, 8, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		reviews []*Review
+		want    string
+	}{
+		{"none due", []*Review{{ProblemID: "a", DueDate: "2020-01-09"}}, ""},
+		{"due today", []*Review{{ProblemID: "a", DueDate: "2020-01-08"}}, "a"},
+		{"oldest first", []*Review{{ProblemID: "a", DueDate: "2020-01-07"}, {ProblemID: "b", DueDate: "2020-01-05"}}, "b"},
+		{"done", []*Review{{ProblemID: "a", DueDate: "2020-01-07", IsDone: true}}, ""},
+		{"sent since due", []*Review{{ProblemID: "a", DueDate: "2020-01-07", SentDate: "2020-01-07"}, {ProblemID: "b", DueDate: "2020-01-08"}}, "b"},
+		{"sent before due", []*Review{{ProblemID: "a", DueDate: "2020-01-08", SentDate: "2020-01-05"}}, "a"},
+	}
+
+	for _, test := range tests {
+		got := ""
+		if due := dueReview(test.reviews, today); due != nil {
+			got = due.ProblemID
+		}
+
+		if got != test.want {
+			t.Errorf("%s: dueReview() = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
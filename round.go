@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// answerKey identifies the answer of a user in a group. A user in two groups
+// of the same cohort answers in each group separately.
+func answerKey(answer *Answer) string {
+	if answer.UserGroupID == "" {
+		return answer.UserID
+	}
+	return answer.UserGroupID + "_" + answer.UserID
+}
+
+func answerID(answer *Answer) string {
+	return answer.ProblemID + "_" + answerKey(answer)
+}
+
+type Round struct {
+	Problem *Problem
+
+	mutex    sync.Mutex
+	answers  map[string]*Answer
+	isClosed bool
+	// pending counts the submissions saving outside the lock.
+	pending sync.WaitGroup
+}
+
+func NewRound(problem *Problem, answers []*Answer) *Round {
+	r := &Round{
+		Problem: problem,
+		answers: map[string]*Answer{},
+	}
+
+	for _, answer := range answers {
+		r.answers[answerKey(answer)] = answer
+	}
+
+	return r
+}
+
+// Submit marks answer pending and runs save without holding the lock, so
+// that answers save in parallel. Close does not return until every pending
+// submission has either been recorded or rejected.
+func (r *Round) Submit(ctx context.Context, answer *Answer, save func(ctx context.Context, answer *Answer) error) (bool, error) {
+	r.mutex.Lock()
+	if r.isClosed {
+		r.mutex.Unlock()
+		return false, nil
+	}
+	r.pending.Add(1)
+	r.mutex.Unlock()
+
+	defer r.pending.Done()
+
+	err := save(ctx, answer)
+	if err != nil {
+		return false, err
+	}
+
+	r.mutex.Lock()
+	r.answers[answerKey(answer)] = answer
+	r.mutex.Unlock()
+
+	return true, nil
+}
+
+// HasAnswered reports whether the user answered the round in any group.
+func (r *Round) HasAnswered(userID UserID) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, answer := range r.answers {
+		if answer.UserID == userID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Round) Close() []*Answer {
+	r.mutex.Lock()
+	r.isClosed = true
+	r.mutex.Unlock()
+
+	// No submission becomes pending once isClosed is set.
+	r.pending.Wait()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	answers := []*Answer{}
+	for _, answer := range r.answers {
+		answers = append(answers, answer)
+	}
+
+	return answers
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a save func that remembers what it stored.
+type recorder struct {
+	mutex sync.Mutex
+	saved map[string]bool
+	delay time.Duration
+}
+
+func (r *recorder) save(ctx context.Context, answer *Answer) error {
+	time.Sleep(r.delay)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.saved[answerKey(answer)] = true
+	return nil
+}
+
+func TestRoundSubmitAndClose(t *testing.T) {
+	for run := 0; run < 20; run++ {
+		round := NewRound(&Problem{ID: "p"}, []*Answer{})
+		store := &recorder{saved: map[string]bool{}, delay: time.Millisecond}
+
+		var mutex sync.Mutex
+		accepted := map[string]bool{}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				answer := &Answer{ProblemID: "p", UserID: strconv.Itoa(i), UserGroupID: "g"}
+				ok, err := round.Submit(context.Background(), answer, store.save)
+				if err != nil {
+					t.Error(err)
+				}
+
+				if ok {
+					mutex.Lock()
+					accepted[answerKey(answer)] = true
+					mutex.Unlock()
+				}
+			}(i)
+		}
+
+		time.Sleep(time.Duration(run) * 100 * time.Microsecond)
+		recorded := round.Close()
+		wg.Wait()
+
+		if len(recorded) != len(accepted) {
+			t.Fatalf("run %d: recorded %d answers, accepted %d", run, len(recorded), len(accepted))
+		}
+
+		for _, answer := range recorded {
+			key := answerKey(answer)
+			if !accepted[key] {
+				t.Errorf("run %d: %s is recorded but not accepted", run, key)
+			}
+			if !store.saved[key] {
+				t.Errorf("run %d: %s is recorded but not saved", run, key)
+			}
+		}
+
+		if len(store.saved) != len(accepted) {
+			t.Errorf("run %d: saved %d answers, accepted %d", run, len(store.saved), len(accepted))
+		}
+	}
+}
+
+func TestRoundCloseWaitsForPendingSubmit(t *testing.T) {
+	round := NewRound(&Problem{ID: "p"}, []*Answer{})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	save := func(ctx context.Context, answer *Answer) error {
+		close(started)
+		<-release
+		return nil
+	}
+
+	submitted := make(chan bool)
+	go func() {
+		ok, _ := round.Submit(context.Background(), &Answer{ProblemID: "p", UserID: "u"}, save)
+		submitted <- ok
+	}()
+	<-started
+
+	closed := make(chan []*Answer)
+	go func() {
+		closed <- round.Close()
+	}()
+
+	select {
+	case <-closed:
+		t.Fatal("Close returned while a submission was pending")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	if !<-submitted {
+		t.Fatal("the pending submission was rejected")
+	}
+	if answers := <-closed; len(answers) != 1 {
+		t.Fatalf("recorded %d answers, want 1", len(answers))
+	}
+
+	ok, _ := round.Submit(context.Background(), &Answer{ProblemID: "p", UserID: "v"}, save)
+	if ok {
+		t.Fatal("a submission after Close was accepted")
+	}
+}
+
+func TestRoundSubmitRollsBackFailedSave(t *testing.T) {
+	round := NewRound(&Problem{ID: "p"}, []*Answer{})
+	failure := errors.New("unavailable")
+
+	ok, err := round.Submit(context.Background(), &Answer{ProblemID: "p", UserID: "u"}, func(ctx context.Context, answer *Answer) error {
+		return failure
+	})
+
+	if ok || err != failure {
+		t.Fatalf("Submit = %t, %v, want false, %v", ok, err, failure)
+	}
+	if round.HasAnswered("u") {
+		t.Fatal("a failed submission is recorded")
+	}
+	if answers := round.Close(); len(answers) != 0 {
+		t.Fatalf("recorded %d answers, want 0", len(answers))
+	}
+}
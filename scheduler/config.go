@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+)
+
+type Config struct {
+	TimeZone string                `json:"timezone"`
+	Jobs     map[string]*JobConfig `json:"jobs"`
+}
+
+type JobConfig struct {
+	Spec    string `json:"spec"`
+	Enabled *bool  `json:"enabled"`
+	CatchUp string `json:"catchUp"`
+}
+
+func (j *JobConfig) IsEnabled() bool {
+	return j != nil && (j.Enabled == nil || *j.Enabled)
+}
+
+// CatchUpWindow is how long after a missed run the job is still worth
+// running on start. Zero disables catching up.
+func (j *JobConfig) CatchUpWindow() (time.Duration, error) {
+	if j.CatchUp == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(j.CatchUp)
+}
+
+// LoadConfig reads the JSON file at path over defaults. Jobs missing from the
+// file keep their default spec and stay enabled.
+func LoadConfig(path string, defaults *Config) (*Config, error) {
+	if path == "" {
+		return defaults, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return defaults, err
+	}
+
+	loaded := &Config{}
+	err = json.Unmarshal(b, loaded)
+	if err != nil {
+		return defaults, err
+	}
+
+	config := &Config{
+		TimeZone: defaults.TimeZone,
+		Jobs:     map[string]*JobConfig{},
+	}
+
+	if loaded.TimeZone != "" {
+		config.TimeZone = loaded.TimeZone
+	}
+
+	for name, job := range defaults.Jobs {
+		copied := *job
+		config.Jobs[name] = &copied
+	}
+
+	for name, job := range loaded.Jobs {
+		merged, ok := config.Jobs[name]
+		if !ok {
+			merged = &JobConfig{}
+			config.Jobs[name] = merged
+		}
+
+		if job.Spec != "" {
+			merged.Spec = job.Spec
+		}
+
+		if job.Enabled != nil {
+			merged.Enabled = job.Enabled
+		}
+
+		if job.CatchUp != "" {
+			merged.CatchUp = job.CatchUp
+		}
+	}
+
+	return config, nil
+}
+
+// Location falls back to a fixed JST offset when the host has no zoneinfo
+// database, so the schedule never silently follows the host time zone.
+func (c *Config) Location() (*time.Location, error) {
+	location, err := time.LoadLocation(c.TimeZone)
+	if err != nil && c.TimeZone == "Asia/Tokyo" {
+		return time.FixedZone("JST", 9*60*60), nil
+	}
+	return location, err
+}
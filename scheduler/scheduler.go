@@ -1,36 +1,382 @@
 package scheduler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/kawasin73/htask/cron"
 )
 
-type Job struct {
-	Cancel func()
+var ErrJobNotFound = errors.New("job not found")
+
+type Task func(ctx context.Context) error
+
+type Status struct {
+	Name      string    `json:"name"`
+	Spec      string    `json:"spec"`
+	IsPaused  bool      `json:"isPaused"`
+	IsRunning bool      `json:"isRunning"`
+	NextRun   time.Time `json:"nextRun"`
+	LastRun   time.Time `json:"lastRun"`
+	LastError string    `json:"lastError"`
 }
 
-var tasks map[string]*Job
-var c *cron.Cron
+type job struct {
+	Status
+	spec     *Spec
+	catchUp  time.Duration
+	task     Task
+	chCancel chan struct{}
+}
 
-func init() {
-	tasks = map[string]*Job{}
+type Scheduler struct {
+	mutex     sync.Mutex
+	cron      *cron.Cron
+	location  *time.Location
+	statePath string
+	jobs      map[string]*job
+	isStarted bool
+}
 
+// New creates a scheduler whose jobs run one at a time. Last run times and
+// pause flags are kept in the JSON file at statePath so that runs missed
+// while the server was down can be caught up on the next start.
+func New(location *time.Location, statePath string) *Scheduler {
 	var wg sync.WaitGroup
-	c = cron.NewCron(&wg, cron.Option{
-		Workers: 1,
+	return &Scheduler{
+		cron: cron.NewCron(&wg, cron.Option{
+			Workers:  1,
+			Location: location,
+		}),
+		location:  location,
+		statePath: statePath,
+		jobs:      map[string]*job{},
+	}
+}
+
+func (s *Scheduler) Register(name string, spec *Spec, catchUp time.Duration, task Task) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if old, ok := s.jobs[name]; ok {
+		old.cancel()
+	}
+
+	j := &job{
+		Status: Status{
+			Name: name,
+			Spec: spec.String(),
+		},
+		spec:    spec,
+		catchUp: catchUp,
+		task:    task,
+	}
+	s.jobs[name] = j
+
+	if s.isStarted {
+		j.chCancel = make(chan struct{})
+		s.schedule(j, time.Now())
+	}
+}
+
+func (s *Scheduler) Start() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := s.loadState()
+	if err != nil {
+		log.Printf("scheduler: failed to load state: %s", err.Error())
+	}
+
+	type catchUp struct {
+		job    *job
+		missed time.Time
+	}
+
+	s.isStarted = true
+	now := time.Now().In(s.location)
+	catchUps := []*catchUp{}
+	for _, j := range s.jobs {
+		j.chCancel = make(chan struct{})
+		s.schedule(j, now)
+
+		if j.IsPaused || j.LastRun.IsZero() || j.catchUp <= 0 {
+			continue
+		}
+
+		missed := lastMissed(j.spec, j.LastRun.In(s.location), now)
+		if !missed.IsZero() && now.Sub(missed) <= j.catchUp {
+			log.Printf("scheduler: %s missed the run at %s, catching up", j.Name, missed)
+			catchUps = append(catchUps, &catchUp{job: j, missed: missed})
+		}
+	}
+
+	// Catch-ups replay in the order they were scheduled, as push_problem
+	// before push_editorial.
+	sort.Slice(catchUps, func(i, k int) bool {
+		if !catchUps[i].missed.Equal(catchUps[k].missed) {
+			return catchUps[i].missed.Before(catchUps[k].missed)
+		}
+		return catchUps[i].job.Name < catchUps[k].job.Name
+	})
+
+	jobs := []*job{}
+	for _, c := range catchUps {
+		jobs = append(jobs, c.job)
+	}
+	s.enqueue(jobs...)
+}
+
+// lastMissed returns the latest time spec matches after lastRun and not after
+// now, or the zero time if there is none.
+func lastMissed(spec *Spec, lastRun time.Time, now time.Time) time.Time {
+	missed := time.Time{}
+	for at := spec.Next(lastRun); !at.IsZero() && !at.After(now); at = spec.Next(at) {
+		missed = at
+	}
+	return missed
+}
+
+func (s *Scheduler) List() []*Status {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	statuses := []*Status{}
+	for _, j := range s.jobs {
+		status := j.Status
+		statuses = append(statuses, &status)
+	}
+
+	sort.Slice(statuses, func(i, k int) bool {
+		return statuses[i].Name < statuses[k].Name
 	})
+
+	return statuses
 }
 
-func Set(key string, maker func(c *cron.Cron) *Job) {
-	if old, ok := tasks[key]; ok {
-		old.Cancel()
+// NextRuns returns the next n times the job is scheduled at.
+func (s *Scheduler) NextRuns(name string, n int) ([]time.Time, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	j, ok := s.jobs[name]
+	if !ok {
+		return []time.Time{}, ErrJobNotFound
 	}
-	tasks[key] = maker(c)
+
+	runs := []time.Time{}
+	at := time.Now().In(s.location)
+	for len(runs) < n {
+		at = j.spec.Next(at)
+		if at.IsZero() {
+			break
+		}
+		runs = append(runs, at)
+	}
+
+	return runs, nil
+}
+
+func (s *Scheduler) Trigger(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	j, ok := s.jobs[name]
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	s.enqueue(j)
+	return nil
+}
+
+func (s *Scheduler) Pause(name string) error {
+	return s.setPaused(name, true)
+}
+
+func (s *Scheduler) Resume(name string) error {
+	return s.setPaused(name, false)
+}
+
+func (s *Scheduler) Cancel(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	j, ok := s.jobs[name]
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	j.cancel()
+	delete(s.jobs, name)
+	return nil
 }
 
-func Cancel(key string) {
-	if job, ok := tasks[key]; ok {
-		job.Cancel()
+func (s *Scheduler) setPaused(name string, isPaused bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	j, ok := s.jobs[name]
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	j.IsPaused = isPaused
+	s.saveState()
+	return nil
+}
+
+func (j *job) cancel() {
+	if j.chCancel == nil {
+		return
+	}
+
+	select {
+	case <-j.chCancel:
+	default:
+		close(j.chCancel)
+	}
+}
+
+// schedule must be called with s.mutex held.
+func (s *Scheduler) schedule(j *job, from time.Time) {
+	next := j.spec.Next(from.In(s.location))
+	j.NextRun = next
+	if next.IsZero() {
+		log.Printf("scheduler: %s never matches %s", j.Name, j.spec)
+		return
+	}
+
+	chCancel := j.chCancel
+	err := s.cron.Set(chCancel, next, func(at time.Time) {
+		s.mutex.Lock()
+		if now := time.Now(); now.After(at) {
+			at = now
+		}
+		s.schedule(j, at)
+		isPaused := j.IsPaused
+		s.mutex.Unlock()
+
+		if isPaused {
+			log.Printf("scheduler: %s is paused, skipping the run at %s", j.Name, next)
+			return
+		}
+
+		s.run(j)
+	})
+
+	if err != nil {
+		log.Printf("scheduler: failed to schedule %s at %s: %s", j.Name, next, err.Error())
+	}
+}
+
+// enqueue runs jobs now, one after another in the given order. It must be
+// called with s.mutex held.
+func (s *Scheduler) enqueue(jobs ...*job) {
+	if len(jobs) == 0 {
+		return
+	}
+
+	err := s.cron.Set(make(chan struct{}), time.Now(), func(_ time.Time) {
+		for _, j := range jobs {
+			s.run(j)
+		}
+	})
+
+	if err != nil {
+		log.Printf("scheduler: failed to enqueue %d jobs: %s", len(jobs), err.Error())
+	}
+}
+
+func (s *Scheduler) run(j *job) {
+	s.mutex.Lock()
+	j.IsRunning = true
+	s.mutex.Unlock()
+
+	err := j.task(context.Background())
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	j.IsRunning = false
+	j.LastRun = time.Now().In(s.location)
+	j.LastError = ""
+	if err != nil {
+		j.LastError = err.Error()
+		log.Printf(`
+			Failed to run %s
+				message %s
+		`, j.Name, err.Error())
+	}
+
+	s.saveState()
+}
+
+type state struct {
+	LastRun   time.Time `json:"lastRun"`
+	LastError string    `json:"lastError"`
+	IsPaused  bool      `json:"isPaused"`
+}
+
+func (s *Scheduler) loadState() error {
+	if s.statePath == "" {
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(s.statePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	states := map[string]*state{}
+	err = json.Unmarshal(b, &states)
+	if err != nil {
+		return err
+	}
+
+	for name, st := range states {
+		if j, ok := s.jobs[name]; ok {
+			j.LastRun = st.LastRun
+			j.LastError = st.LastError
+			j.IsPaused = st.IsPaused
+		}
+	}
+
+	return nil
+}
+
+// saveState must be called with s.mutex held.
+func (s *Scheduler) saveState() {
+	if s.statePath == "" {
+		return
+	}
+
+	states := map[string]*state{}
+	for name, j := range s.jobs {
+		states[name] = &state{
+			LastRun:   j.LastRun,
+			LastError: j.LastError,
+			IsPaused:  j.IsPaused,
+		}
+	}
+
+	b, err := json.MarshalIndent(states, "", "\t")
+	if err == nil {
+		err = ioutil.WriteFile(s.statePath, b, 0644)
+	}
+
+	if err != nil {
+		log.Printf("scheduler: failed to save state: %s", err.Error())
 	}
 }
@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLastMissed(t *testing.T) {
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2020, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	// 2020-01-07 is a Tuesday.
+	tests := []struct {
+		name    string
+		expr    string
+		lastRun time.Time
+		now     time.Time
+		want    time.Time
+	}{
+		{"none missed", "0 19 * * *", at(7, 19, 0), at(8, 18, 0), time.Time{}},
+		{"one missed", "0 19 * * *", at(7, 19, 0), at(8, 19, 30), at(8, 19, 0)},
+		{"several missed", "0 19 * * *", at(6, 19, 0), at(8, 19, 30), at(8, 19, 0)},
+		{"missed exactly now", "0 19 * * *", at(7, 19, 0), at(8, 19, 0), at(8, 19, 0)},
+		{"every 15 minutes", "*/15 * * * *", at(7, 0, 0), at(8, 10, 20), at(8, 10, 15)},
+	}
+
+	for _, test := range tests {
+		spec, err := Parse(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := lastMissed(spec, test.lastRun, test.now); !got.Equal(test.want) {
+			t.Errorf("%s: lastMissed(%q, %s, %s) = %s, want %s", test.name, test.expr, test.lastRun, test.now, got, test.want)
+		}
+	}
+}
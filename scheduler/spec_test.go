@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSpecNext(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(location *time.Location, month time.Month, day int, hour int, minute int) time.Time {
+		return time.Date(2020, month, day, hour, minute, 0, 0, location)
+	}
+
+	// 2020-01-01 is a Wednesday.
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"strictly after", "0 0 * * *", at(time.UTC, 1, 1, 0, 0), at(time.UTC, 1, 2, 0, 0)},
+		{"next day", "30 9 * * *", at(time.UTC, 1, 1, 10, 0), at(time.UTC, 1, 2, 9, 30)},
+		{"range", "0 9-17 * * *", at(time.UTC, 1, 1, 17, 30), at(time.UTC, 1, 2, 9, 0)},
+		{"list", "0 9,18 * * *", at(time.UTC, 1, 1, 10, 0), at(time.UTC, 1, 1, 18, 0)},
+		{"step of star", "*/15 * * * *", at(time.UTC, 1, 1, 0, 7), at(time.UTC, 1, 1, 0, 15)},
+		{"step from value", "5/20 * * * *", at(time.UTC, 1, 1, 0, 26), at(time.UTC, 1, 1, 0, 45)},
+		{"step of range", "0 0-12/6 * * *", at(time.UTC, 1, 1, 7, 0), at(time.UTC, 1, 1, 12, 0)},
+		{"sunday as 0", "0 20 * * 0", at(time.UTC, 1, 1, 0, 0), at(time.UTC, 1, 5, 20, 0)},
+		{"sunday as 7", "0 20 * * 7", at(time.UTC, 1, 1, 0, 0), at(time.UTC, 1, 5, 20, 0)},
+		{"weekday range", "0 9 * * 1-5", at(time.UTC, 1, 3, 10, 0), at(time.UTC, 1, 6, 9, 0)},
+		{"day of month only", "0 0 13 * *", at(time.UTC, 1, 1, 0, 0), at(time.UTC, 1, 13, 0, 0)},
+		{"day of week only", "0 0 * * 5", at(time.UTC, 1, 1, 0, 0), at(time.UTC, 1, 3, 0, 0)},
+		{"day of month or week by week", "0 0 13 * 5", at(time.UTC, 1, 3, 0, 0), at(time.UTC, 1, 10, 0, 0)},
+		{"day of month or week by month", "0 0 13 * 5", at(time.UTC, 1, 10, 0, 0), at(time.UTC, 1, 13, 0, 0)},
+		{"month", "0 0 1 3 *", at(time.UTC, 1, 1, 0, 0), at(time.UTC, 3, 1, 0, 0)},
+		{"macro", "@monthly", at(time.UTC, 1, 1, 0, 0), at(time.UTC, 2, 1, 0, 0)},
+		{"location", "0 9 * * *", at(tokyo, 1, 1, 10, 0), at(tokyo, 1, 2, 9, 0)},
+		{"never", "0 0 30 2 *", at(time.UTC, 1, 1, 0, 0), time.Time{}},
+	}
+
+	for _, test := range tests {
+		spec, err := Parse(test.expr)
+		if err != nil {
+			t.Errorf("%s: Parse(%q) failed: %v", test.name, test.expr, err)
+			continue
+		}
+
+		if got := spec.Next(test.from); !got.Equal(test.want) {
+			t.Errorf("%s: Parse(%q).Next(%s) = %s, want %s", test.name, test.expr, test.from, got, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 0 *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"0-60 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"1-x * * * *",
+		"@never",
+	}
+
+	for _, expr := range tests {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expr)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/kuolc/oneLeg/json_"
+)
+
+type Schema struct {
+	ProblemSheet string         `json:"problemSheet"`
+	MapSheet     string         `json:"mapSheet"`
+	PageSize     int            `json:"pageSize"`
+	Problem      *ProblemSchema `json:"problem"`
+	Map          *MapSchema     `json:"map"`
+}
+
+type ProblemSchema struct {
+	Index            string `json:"index"`
+	OriginalImageID  string `json:"originalImageID"`
+	ProblemImageID   string `json:"problemImageID"`
+	Text             string `json:"text"`
+	Setter           string `json:"setter"`
+	Difficulty       string `json:"difficulty"`
+	OptionPrefix     string `json:"optionPrefix"`
+	EditorialImageID string `json:"editorialImageID"`
+	Editorial        string `json:"editorial"`
+	Note             string `json:"note"`
+	ScheduledDate    string `json:"scheduledDate"`
+	Submitted        string `json:"submitted"`
+	// Correct holds the correct options as 1-based numbers or option texts,
+	// separated by commas. Problems without one are polls.
+	Correct string `json:"correct"`
+	// CalibratedDifficulty is written back by the calibration job when the
+	// sheet has the column.
+	CalibratedDifficulty string `json:"calibratedDifficulty"`
+}
+
+type MapSchema struct {
+	Name       string `json:"name"`
+	Year       string `json:"year"`
+	Event      string `json:"event"`
+	Regulation string `json:"regulation"`
+	URLPrefix  string `json:"urlPrefix"`
+}
+
+func DefaultSchema() *Schema {
+	return &Schema{
+		ProblemSheet: "問題",
+		MapSheet:     "地図",
+		PageSize:     1000,
+		Problem: &ProblemSchema{
+			Index:                "番号",
+			OriginalImageID:      "元画像ID",
+			ProblemImageID:       "出題画像ID",
+			Text:                 "出題文",
+			Setter:               "出題者",
+			Difficulty:           "難易度",
+			OptionPrefix:         "選択肢",
+			EditorialImageID:     "解説画像ID",
+			Editorial:            "解説文",
+			Note:                 "備考",
+			ScheduledDate:        "出題予定日",
+			Submitted:            "出題済",
+			Correct:              "正解",
+			CalibratedDifficulty: "推定難易度",
+		},
+		Map: &MapSchema{
+			Name:       "テレイン名",
+			Year:       "年度",
+			Event:      "イベント名",
+			Regulation: "競技形式",
+			URLPrefix:  "URL",
+		},
+	}
+}
+
+func LoadSchema(path string) (*Schema, error) {
+	schema := DefaultSchema()
+	if path == "" {
+		return schema, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return schema, err
+	}
+
+	err = json_.Unmarshal(b, schema)
+	if err != nil {
+		return schema, err
+	}
+
+	if schema.PageSize <= 0 {
+		schema.PageSize = DefaultSchema().PageSize
+	}
+
+	return schema, nil
+}
+
+// normalizeDate accepts 2006-01-02, 2006/01/02 and 2006/1/2 and returns
+// 2006-01-02, or "" when the value is not a date.
+func normalizeDate(value string) string {
+	value = strings.TrimSpace(strings.Replace(value, "/", "-", -1))
+	if value == "" {
+		return ""
+	}
+
+	date, err := time.Parse("2006-1-2", value)
+	if err != nil {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
+
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+func columnIndex(header []interface{}, name string) int {
+	for index, value := range header {
+		if value == name {
+			return index
+		}
+	}
+	return -1
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kuolc/oneLeg/json_"
+)
+
+type SelectionStrategy = string
+
+const (
+	SelectionStrategyRandom             = "random"
+	SelectionStrategySheetOrder         = "sheet_order"
+	SelectionStrategyScheduledDate      = "scheduled_date"
+	SelectionStrategyDifficultyRotation = "difficulty_rotation"
+)
+
+type SelectionConfig struct {
+	Strategy        SelectionStrategy `json:"strategy"`
+	AvoidSameSetter bool              `json:"avoidSameSetter"`
+	// WeekdayDifficulty maps "sun" ... "sat" to the difficulty pushed on that
+	// weekday. Used by difficulty_rotation.
+	WeekdayDifficulty map[string]int `json:"weekdayDifficulty"`
+}
+
+type Selector struct {
+	config   *SelectionConfig
+	location *time.Location
+}
+
+func LoadSelectionConfig(path string) (*SelectionConfig, error) {
+	config := &SelectionConfig{
+		Strategy: SelectionStrategyRandom,
+	}
+
+	if path == "" {
+		return config, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+
+	err = json_.Unmarshal(b, config)
+	return config, err
+}
+
+func NewSelector(config *SelectionConfig, location *time.Location) (*Selector, error) {
+	switch config.Strategy {
+	case SelectionStrategyRandom, SelectionStrategySheetOrder, SelectionStrategyScheduledDate, SelectionStrategyDifficultyRotation:
+	default:
+		return nil, fmt.Errorf("unknown selection strategy %q", config.Strategy)
+	}
+
+	return &Selector{
+		config:   config,
+		location: location,
+	}, nil
+}
+
+// Select picks the problem to push on day. The choice depends only on the
+// arguments, so Queue can preview exactly what later pushes will select.
+func (s *Selector) Select(problems []*Problem, last *Problem, day time.Time) *Problem {
+	candidates := append([]*Problem{}, problems...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Index < candidates[j].Index
+	})
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	day = day.In(s.location)
+	if s.config.Strategy == SelectionStrategyScheduledDate {
+		return s.selectByScheduledDate(candidates, last, day)
+	}
+
+	candidates = s.avoidSameSetter(candidates, last)
+	switch s.config.Strategy {
+	case SelectionStrategySheetOrder:
+		return candidates[0]
+	case SelectionStrategyDifficultyRotation:
+		return s.selectByDifficulty(candidates, day)
+	default:
+		hash := fnv.New64a()
+		hash.Write([]byte(day.Format("2006-01-02")))
+		random := rand.New(rand.NewSource(int64(hash.Sum64())))
+		return candidates[random.Intn(len(candidates))]
+	}
+}
+
+// avoidSameSetter leaves out the problems by the setter of last, unless that
+// leaves nothing.
+func (s *Selector) avoidSameSetter(candidates []*Problem, last *Problem) []*Problem {
+	if !s.config.AvoidSameSetter || last == nil || last.Setter == "" {
+		return candidates
+	}
+
+	others := []*Problem{}
+	for _, problem := range candidates {
+		if problem.Setter != last.Setter {
+			others = append(others, problem)
+		}
+	}
+
+	if len(others) == 0 {
+		return candidates
+	}
+	return others
+}
+
+// selectByScheduledDate prefers the problem dated day, then the oldest
+// overdue one. Dated problems are pushed whoever set them; only the undated
+// fallback avoids the setter of last.
+func (s *Selector) selectByScheduledDate(candidates []*Problem, last *Problem, day time.Time) *Problem {
+	today := day.Format("2006-01-02")
+
+	var overdue *Problem
+	undated := []*Problem{}
+	for _, problem := range candidates {
+		date := problem.ScheduledDate
+		switch {
+		case date == today:
+			return problem
+		case date == "":
+			undated = append(undated, problem)
+		case date < today:
+			if overdue == nil || date < overdue.ScheduledDate {
+				overdue = problem
+			}
+		}
+	}
+
+	if overdue != nil {
+		return overdue
+	}
+
+	if len(undated) == 0 {
+		return nil
+	}
+	return s.avoidSameSetter(undated, last)[0]
+}
+
+func (s *Selector) selectByDifficulty(candidates []*Problem, day time.Time) *Problem {
+	weekday := strings.ToLower(day.Weekday().String()[:3])
+	difficulty, ok := s.config.WeekdayDifficulty[weekday]
+	if !ok {
+		return candidates[0]
+	}
+
+	best := candidates[0]
+	for _, problem := range candidates[1:] {
+		if abs(problem.Difficulty-difficulty) < abs(best.Difficulty-difficulty) {
+			best = problem
+		}
+	}
+
+	return best
+}
+
+// Queue previews the problems pushed on each of days in turn.
+func (s *Selector) Queue(problems []*Problem, last *Problem, days []time.Time) []*Problem {
+	remaining := append([]*Problem{}, problems...)
+	queue := []*Problem{}
+	for _, day := range days {
+		problem := s.Select(remaining, last, day)
+		if problem == nil {
+			break
+		}
+
+		queue = append(queue, problem)
+		last = problem
+
+		for index, candidate := range remaining {
+			if candidate == problem {
+				remaining = append(remaining[:index], remaining[index+1:]...)
+				break
+			}
+		}
+	}
+
+	return queue
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
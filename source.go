@@ -0,0 +1,21 @@
+package main
+
+import (
+	"context"
+	"io"
+)
+
+// ProblemSource reads problems from a pool. The empty pool is the default
+// problem sheet of the schema.
+type ProblemSource interface {
+	ReadProblems(ctx context.Context, pool string) ([]*Problem, error)
+	SetProblemSubmitted(ctx context.Context, problem *Problem) error
+	// SetCalibratedDifficulties writes difficulties by problem index of pool
+	// where the source has a place for them.
+	SetCalibratedDifficulties(ctx context.Context, pool string, difficulties map[int]int) error
+	OpenImage(ctx context.Context, imageURL string) (io.ReadCloser, error)
+}
+
+type MapSource interface {
+	ReadOMaps(ctx context.Context) ([]*OMap, error)
+}
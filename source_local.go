@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+type localSource struct {
+	dir     string
+	baseURL string
+	schema  *Schema
+	mutex   sync.Mutex
+}
+
+type localRow struct {
+	header []interface{}
+	row    []interface{}
+}
+
+func NewLocalSource(dir string, baseURL string, schema *Schema) *localSource {
+	return &localSource{
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		schema:  schema,
+	}
+}
+
+func (s *localSource) ImageDir() string {
+	return filepath.Join(s.dir, "images")
+}
+
+func (s *localSource) imageURL(imageID string) string {
+	if strings.HasPrefix(imageID, "http://") || strings.HasPrefix(imageID, "https://") {
+		return imageID
+	}
+	return s.baseURL + "/images/" + url.PathEscape(imageID)
+}
+
+func (s *localSource) OpenImage(ctx context.Context, imageURL string) (io.ReadCloser, error) {
+	prefix := s.baseURL + "/images/"
+	if strings.HasPrefix(imageURL, prefix) {
+		name, err := url.PathUnescape(strings.TrimPrefix(imageURL, prefix))
+		if err != nil {
+			return nil, err
+		}
+		return os.Open(filepath.Join(s.ImageDir(), filepath.Base(name)))
+	}
+
+	request, err := http.NewRequest(http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := http.DefaultClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Body, nil
+}
+
+func (s *localSource) readRows(name string) ([]*localRow, error) {
+	for _, ext := range []string{".csv", ".yaml", ".yml"} {
+		b, err := ioutil.ReadFile(filepath.Join(s.dir, name+ext))
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return []*localRow{}, err
+		}
+
+		if ext == ".csv" {
+			return s.parseCSV(b)
+		}
+		return s.parseYAML(b)
+	}
+
+	return []*localRow{}, fmt.Errorf("%s.csv or %s.yaml not found in %s", name, name, s.dir)
+}
+
+func (s *localSource) parseCSV(b []byte) ([]*localRow, error) {
+	records, err := csv.NewReader(strings.NewReader(string(b))).ReadAll()
+	if err != nil {
+		return []*localRow{}, err
+	}
+
+	if len(records) == 0 {
+		return []*localRow{}, nil
+	}
+
+	header := []interface{}{}
+	for _, value := range records[0] {
+		header = append(header, value)
+	}
+
+	rows := []*localRow{}
+	for _, record := range records[1:] {
+		row := []interface{}{}
+		for _, value := range record {
+			row = append(row, value)
+		}
+		rows = append(rows, &localRow{header: header, row: row})
+	}
+
+	return rows, nil
+}
+
+func (s *localSource) parseYAML(b []byte) ([]*localRow, error) {
+	items := []yaml.MapSlice{}
+	err := yaml.Unmarshal(b, &items)
+	if err != nil {
+		return []*localRow{}, err
+	}
+
+	rows := []*localRow{}
+	for _, item := range items {
+		header := []interface{}{}
+		row := []interface{}{}
+		for _, field := range item {
+			value := ""
+			if field.Value != nil {
+				value = fmt.Sprint(field.Value)
+			}
+
+			header = append(header, fmt.Sprint(field.Key))
+			row = append(row, value)
+		}
+		rows = append(rows, &localRow{header: header, row: row})
+	}
+
+	return rows, nil
+}
+
+// problemsName is the file name of pool without its extension.
+func (s *localSource) problemsName(pool string) string {
+	if pool == "" {
+		return "problems"
+	}
+	return pool
+}
+
+func (s *localSource) submittedPath(pool string) string {
+	if pool == "" {
+		return filepath.Join(s.dir, "submitted.json")
+	}
+	return filepath.Join(s.dir, "submitted_"+pool+".json")
+}
+
+func (s *localSource) readSubmitted(pool string) (map[int]bool, error) {
+	submitted := map[int]bool{}
+
+	b, err := ioutil.ReadFile(s.submittedPath(pool))
+	if os.IsNotExist(err) {
+		return submitted, nil
+	}
+
+	if err != nil {
+		return submitted, err
+	}
+
+	indexes := []int{}
+	err = json.Unmarshal(b, &indexes)
+	if err != nil {
+		return submitted, err
+	}
+
+	for _, index := range indexes {
+		submitted[index] = true
+	}
+
+	return submitted, nil
+}
+
+func (s *localSource) ReadProblems(ctx context.Context, pool string) ([]*Problem, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rows, err := s.readRows(s.problemsName(pool))
+	if err != nil {
+		return []*Problem{}, err
+	}
+
+	submitted, err := s.readSubmitted(pool)
+	if err != nil {
+		return []*Problem{}, err
+	}
+
+	problems := []*Problem{}
+	for _, row := range rows {
+		problem := &Problem{Pool: pool}
+		if problem.FromRow(s.schema.Problem, row.header, row.row, s.imageURL) && !problem.HasSubmitted && !submitted[problem.Index] {
+			problems = append(problems, problem)
+		}
+	}
+
+	return problems, nil
+}
+
+func (s *localSource) SetProblemSubmitted(ctx context.Context, problem *Problem) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	submitted, err := s.readSubmitted(problem.Pool)
+	if err != nil {
+		return err
+	}
+
+	submitted[problem.Index] = true
+
+	indexes := []int{}
+	for index := range submitted {
+		indexes = append(indexes, index)
+	}
+
+	b, err := json.Marshal(indexes)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(s.submittedPath(problem.Pool), b, 0644)
+}
+
+// SetCalibratedDifficulties does nothing. Local files are edited by hand and
+// the calibrated difficulties stay in the store.
+func (s *localSource) SetCalibratedDifficulties(ctx context.Context, pool string, difficulties map[int]int) error {
+	return nil
+}
+
+func (s *localSource) ReadOMaps(ctx context.Context) ([]*OMap, error) {
+	rows, err := s.readRows("maps")
+	if err != nil {
+		return []*OMap{}, err
+	}
+
+	maps := []*OMap{}
+	for _, row := range rows {
+		omap := new(OMap)
+		if omap.FromRow(s.schema.Map, row.header, row.row) {
+			maps = append(maps, omap)
+		}
+	}
+
+	return maps, nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/kuolc/oneLeg/google_"
+	"google.golang.org/api/sheets/v4"
+)
+
+type sheetsSource struct {
+	client  *google_.GoogleClient
+	sheetID string
+	schema  *Schema
+}
+
+func NewSheetsSource(client *google_.GoogleClient, sheetID string, schema *Schema) *sheetsSource {
+	return &sheetsSource{
+		client:  client,
+		sheetID: sheetID,
+		schema:  schema,
+	}
+}
+
+func (s *sheetsSource) imageURL(imageID string) string {
+	return "https://drive.google.com/uc?export=view&id=" + imageID
+}
+
+func (s *sheetsSource) OpenImage(ctx context.Context, imageURL string) (io.ReadCloser, error) {
+	return s.client.OpenImage(ctx, imageURL)
+}
+
+func (s *sheetsSource) getValues(ctx context.Context, valueRange string) (*sheets.ValueRange, error) {
+	ctx, cancel := s.client.WithTimeout(ctx)
+	defer cancel()
+
+	return s.client.Sheets.Spreadsheets.Values.Get(s.sheetID, valueRange).Context(ctx).Do()
+}
+
+func (s *sheetsSource) readHeader(ctx context.Context, sheet string) ([]interface{}, error) {
+	valueRange, err := s.getValues(ctx, fmt.Sprintf("%s!1:1", sheet))
+	if err != nil {
+		return []interface{}{}, err
+	}
+
+	if len(valueRange.Values) == 0 {
+		return []interface{}{}, fmt.Errorf("header row of %s is empty", sheet)
+	}
+
+	return valueRange.Values[0], nil
+}
+
+func (s *sheetsSource) readRows(ctx context.Context, sheet string, header []interface{}, callback func(rowNumber int, row []interface{})) error {
+	lastColumn := columnName(len(header) - 1)
+	for start := 2; ; start += s.schema.PageSize {
+		end := start + s.schema.PageSize - 1
+		valueRange, err := s.getValues(ctx, fmt.Sprintf("%s!A%d:%s%d", sheet, start, lastColumn, end))
+		if err != nil {
+			return err
+		}
+
+		if len(valueRange.Values) == 0 {
+			return nil
+		}
+
+		for index, row := range valueRange.Values {
+			callback(start+index, row)
+		}
+	}
+}
+
+func (s *sheetsSource) problemSheet(pool string) string {
+	if pool == "" {
+		return s.schema.ProblemSheet
+	}
+	return pool
+}
+
+func (s *sheetsSource) ReadProblems(ctx context.Context, pool string) ([]*Problem, error) {
+	sheet := s.problemSheet(pool)
+	header, err := s.readHeader(ctx, sheet)
+	if err != nil {
+		return []*Problem{}, err
+	}
+
+	problems := []*Problem{}
+	err = s.readRows(ctx, sheet, header, func(rowNumber int, row []interface{}) {
+		problem := &Problem{Row: rowNumber, Pool: pool}
+		if problem.FromRow(s.schema.Problem, header, row, s.imageURL) && !problem.HasSubmitted {
+			problems = append(problems, problem)
+		}
+	})
+
+	if err != nil {
+		return []*Problem{}, err
+	}
+
+	return problems, nil
+}
+
+func (s *sheetsSource) SetProblemSubmitted(ctx context.Context, problem *Problem) error {
+	sheet := s.problemSheet(problem.Pool)
+	header, err := s.readHeader(ctx, sheet)
+	if err != nil {
+		return err
+	}
+
+	column := columnIndex(header, s.schema.Problem.Submitted)
+	if column < 0 {
+		return fmt.Errorf("column %s not found in %s", s.schema.Problem.Submitted, sheet)
+	}
+
+	rowNumber := problem.Row
+	if rowNumber == 0 {
+		rowNumber = problem.Index + 1
+	}
+
+	ctx, cancel := s.client.WithTimeout(ctx)
+	defer cancel()
+
+	_, err = s.client.Sheets.Spreadsheets.Values.Update(s.sheetID, fmt.Sprintf("%s!%s%d", sheet, columnName(column), rowNumber), &sheets.ValueRange{
+		Values: [][]interface{}{
+			[]interface{}{
+				"1",
+			},
+		},
+	}).ValueInputOption("USER_ENTERED").Context(ctx).Do()
+
+	return err
+}
+
+func (s *sheetsSource) SetCalibratedDifficulties(ctx context.Context, pool string, difficulties map[int]int) error {
+	sheet := s.problemSheet(pool)
+	header, err := s.readHeader(ctx, sheet)
+	if err != nil {
+		return err
+	}
+
+	column := columnIndex(header, s.schema.Problem.CalibratedDifficulty)
+	if column < 0 || s.schema.Problem.CalibratedDifficulty == "" {
+		return nil
+	}
+
+	indexColumn := columnIndex(header, s.schema.Problem.Index)
+	if indexColumn < 0 {
+		return fmt.Errorf("column %s not found in %s", s.schema.Problem.Index, sheet)
+	}
+
+	data := []*sheets.ValueRange{}
+	err = s.readRows(ctx, sheet, header, func(rowNumber int, row []interface{}) {
+		if indexColumn >= len(row) {
+			return
+		}
+
+		index, err := strconv.Atoi(fmt.Sprint(row[indexColumn]))
+		if err != nil {
+			return
+		}
+
+		if difficulty, ok := difficulties[index]; ok {
+			data = append(data, &sheets.ValueRange{
+				Range:  fmt.Sprintf("%s!%s%d", sheet, columnName(column), rowNumber),
+				Values: [][]interface{}{{difficulty}},
+			})
+		}
+	})
+
+	if err != nil || len(data) == 0 {
+		return err
+	}
+
+	ctx, cancel := s.client.WithTimeout(ctx)
+	defer cancel()
+
+	_, err = s.client.Sheets.Spreadsheets.Values.BatchUpdate(s.sheetID, &sheets.BatchUpdateValuesRequest{
+		ValueInputOption: "USER_ENTERED",
+		Data:             data,
+	}).Context(ctx).Do()
+
+	return err
+}
+
+func (s *sheetsSource) ReadOMaps(ctx context.Context) ([]*OMap, error) {
+	header, err := s.readHeader(ctx, s.schema.MapSheet)
+	if err != nil {
+		return []*OMap{}, err
+	}
+
+	maps := []*OMap{}
+	err = s.readRows(ctx, s.schema.MapSheet, header, func(rowNumber int, row []interface{}) {
+		omap := new(OMap)
+		if omap.FromRow(s.schema.Map, header, row) {
+			maps = append(maps, omap)
+		}
+	})
+
+	if err != nil {
+		return []*OMap{}, err
+	}
+
+	return maps, nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/kuolc/oneLeg/consts"
+)
+
+type OptionCount struct {
+	Option string `json:"option"`
+	Count  int    `json:"count"`
+}
+
+type UserStats struct {
+	AnsweredCount int `json:"answeredCount"`
+	// Streak counts the latest problems of the cohort answered in a row. An
+	// open problem not answered yet does not break it.
+	Streak          int            `json:"streak"`
+	JudgedCount     int            `json:"judgedCount"`
+	CorrectCount    int            `json:"correctCount"`
+	ComparedCount   int            `json:"comparedCount"`
+	AgreedCount     int            `json:"agreedCount"`
+	FavoriteOptions []*OptionCount `json:"favoriteOptions"`
+}
+
+func rate(count int, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return count * 100 / total
+}
+
+// computeUserStats summarizes answers of a user. problems are every problem
+// newest first, and isOpen tells the problems still taking answers.
+func computeUserStats(problems []*Problem, answers []*Answer, cohort string, isOpen func(problemID ProblemID) bool) *UserStats {
+	stats := &UserStats{FavoriteOptions: []*OptionCount{}}
+
+	problemByID := map[ProblemID]*Problem{}
+	for _, problem := range problems {
+		problemByID[problem.ID] = problem
+	}
+
+	// A user in several groups answers a problem once per group. Only the
+	// first answer of each problem counts.
+	answerByProblem := map[ProblemID]*Answer{}
+	for _, answer := range answers {
+		if _, ok := answerByProblem[answer.ProblemID]; !ok {
+			answerByProblem[answer.ProblemID] = answer
+		}
+	}
+
+	optionCounts := map[string]int{}
+	for problemID, answer := range answerByProblem {
+		stats.AnsweredCount++
+
+		if answer.IsCorrect != nil {
+			stats.JudgedCount++
+			if *answer.IsCorrect {
+				stats.CorrectCount++
+			}
+		}
+
+		problem, ok := problemByID[problemID]
+		if !ok {
+			continue
+		}
+
+		if len(problem.MajorityOptions) > 0 {
+			stats.ComparedCount++
+			for _, option := range problem.MajorityOptions {
+				if option == answer.Option {
+					stats.AgreedCount++
+					break
+				}
+			}
+		}
+
+		if answer.Option >= 0 && answer.Option < len(problem.Options) {
+			optionCounts[problem.Options[answer.Option]]++
+		}
+	}
+
+	for _, problem := range problems {
+		if cohortName(problem.Cohort) != cohort {
+			continue
+		}
+
+		if _, ok := answerByProblem[problem.ID]; ok {
+			stats.Streak++
+			continue
+		}
+
+		if stats.Streak == 0 && isOpen(problem.ID) {
+			continue
+		}
+		break
+	}
+
+	for option, count := range optionCounts {
+		stats.FavoriteOptions = append(stats.FavoriteOptions, &OptionCount{Option: option, Count: count})
+	}
+
+	sort.Slice(stats.FavoriteOptions, func(i, j int) bool {
+		if stats.FavoriteOptions[i].Count != stats.FavoriteOptions[j].Count {
+			return stats.FavoriteOptions[i].Count > stats.FavoriteOptions[j].Count
+		}
+		return stats.FavoriteOptions[i].Option < stats.FavoriteOptions[j].Option
+	})
+
+	if len(stats.FavoriteOptions) > 3 {
+		stats.FavoriteOptions = stats.FavoriteOptions[:3]
+	}
+
+	return stats
+}
+
+func (h *AppHandler) UserStats(ctx context.Context, userID UserID, cohort string) (*UserStats, error) {
+	problems, err := h.problemStore.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	answers, err := h.answerStore.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return computeUserStats(problems, answers, cohort, func(problemID ProblemID) bool {
+		return h.roundOf(problemID) != nil
+	}), nil
+}
+
+func (h *AppHandler) replyStats(c *CommandContext) error {
+	userID := c.Event.Source.UserID
+	if userID == "" {
+		return nil
+	}
+
+	user, err := h.userStore.Get(c, userID)
+	if err == ErrNotFound {
+		user = &User{ID: userID}
+	} else if err != nil {
+		return err
+	}
+
+	// In 1:1 chats the streak follows the group the user last answered in.
+	groupID := c.Event.Source.GroupID
+	if groupID == "" {
+		groupID = user.GroupID
+	}
+
+	stats, err := h.UserStats(c, userID, h.cohortOf(c, groupID))
+	if err != nil {
+		return err
+	}
+
+	return h.replyFlexMessage(c.Client, c.Event.ReplyToken, "成績", consts.StatsTemplatePath(), map[string]interface{}{
+		"userName":        user.DisplayName(h.profileName(c)),
+		"answeredCount":   stats.AnsweredCount,
+		"streak":          stats.Streak,
+		"hasJudged":       stats.JudgedCount > 0,
+		"correctRate":     rate(stats.CorrectCount, stats.JudgedCount),
+		"judgedCount":     stats.JudgedCount,
+		"hasCompared":     stats.ComparedCount > 0,
+		"agreementRate":   rate(stats.AgreedCount, stats.ComparedCount),
+		"comparedCount":   stats.ComparedCount,
+		"favoriteOptions": stats.FavoriteOptions,
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrNotFound = errors.New("not found")
+
+type Stores struct {
+	Problems ProblemStore
+	Answers  AnswerStore
+	// PracticeAnswers keeps the answers of the 練習 command apart from the
+	// daily answers, so that they count toward no stats.
+	PracticeAnswers AnswerStore
+	Users           UserStore
+	Groups          GroupStore
+	Reviews         ReviewStore
+}
+
+type ProblemStore interface {
+	Create(ctx context.Context, problem *Problem) error
+	Get(ctx context.Context, problemID ProblemID) (*Problem, error)
+	Open(ctx context.Context, cohort string) (*Problem, error)
+	Latest(ctx context.Context, cohort string) (*Problem, error)
+	// List returns every problem, newest first.
+	List(ctx context.Context) ([]*Problem, error)
+	// Close closes the problem, recording the options most answered, if any.
+	Close(ctx context.Context, problemID ProblemID, majorityOptions []int) error
+	SaveCalibration(ctx context.Context, problemID ProblemID, rating float64, difficulty int) error
+}
+
+type AnswerStore interface {
+	Save(ctx context.Context, answer *Answer) error
+	ListByProblem(ctx context.Context, problemID ProblemID) ([]*Answer, error)
+	ListByUser(ctx context.Context, userID UserID) ([]*Answer, error)
+}
+
+type UserStore interface {
+	Get(ctx context.Context, userID UserID) (*User, error)
+	Create(ctx context.Context, user *User) error
+	// Update writes only fields, keyed by their JSON names, leaving the rest
+	// of the user as stored. A missing user is created with fields.
+	Update(ctx context.Context, userID UserID, fields map[string]interface{}) error
+	ListReminded(ctx context.Context) ([]*User, error)
+	ListReviewing(ctx context.Context) ([]*User, error)
+}
+
+type GroupStore interface {
+	Get(ctx context.Context, groupID GroupID) (*Group, error)
+	List(ctx context.Context) ([]*Group, error)
+	Save(ctx context.Context, group *Group) error
+}
+
+type ReviewStore interface {
+	Get(ctx context.Context, reviewID string) (*Review, error)
+	ListByUser(ctx context.Context, userID UserID) ([]*Review, error)
+	Save(ctx context.Context, review *Review) error
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/kuolc/oneLeg/json_"
+	"google.golang.org/api/iterator"
+)
+
+func NewFirestoreStores(client *firestore.Client) *Stores {
+	return &Stores{
+		Problems:        NewFirestoreProblemStore(client),
+		Answers:         NewFirestoreAnswerStore(client),
+		PracticeAnswers: NewFirestorePracticeAnswerStore(client),
+		Users:           NewFirestoreUserStore(client),
+		Groups:          NewFirestoreGroupStore(client),
+		Reviews:         NewFirestoreReviewStore(client),
+	}
+}
+
+type firestoreProblemStore struct {
+	client *firestore.Client
+}
+
+func NewFirestoreProblemStore(client *firestore.Client) ProblemStore {
+	return &firestoreProblemStore{client: client}
+}
+
+func (s *firestoreProblemStore) Create(ctx context.Context, problem *Problem) error {
+	data := json_.ToMap(problem)
+	data["isOpen"] = true
+	data["createdAt"] = firestore.ServerTimestamp
+	problemRef, _, err := s.client.Collection("problems").Add(ctx, data)
+	if err != nil {
+		return err
+	}
+
+	problem.ID = problemRef.ID
+	problem.CreatedAt = time.Now()
+	return nil
+}
+
+func (s *firestoreProblemStore) Get(ctx context.Context, problemID ProblemID) (*Problem, error) {
+	problemSnapshot, err := s.client.Collection("problems").Doc(problemID).Get(ctx)
+	if problemSnapshot != nil && !problemSnapshot.Exists() {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	problem := new(Problem)
+	err = problemSnapshot.DataTo(problem)
+	if err != nil {
+		return nil, err
+	}
+
+	problem.ID = problemSnapshot.Ref.ID
+	problem.CreatedAt = problemSnapshot.CreateTime
+	return problem, nil
+}
+
+// Open and Latest filter cohorts here rather than in the query. Problems
+// created before cohorts existed have no cohort field and belong to the
+// default cohort.
+func (s *firestoreProblemStore) Open(ctx context.Context, cohort string) (*Problem, error) {
+	problemSnapshots, err := s.client.Collection("problems").Where("isOpen", "==", true).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *Problem
+	var latestSnapshot *firestore.DocumentSnapshot
+	for _, problemSnapshot := range problemSnapshots {
+		if latestSnapshot != nil && !problemSnapshot.CreateTime.After(latestSnapshot.CreateTime) {
+			continue
+		}
+
+		problem := new(Problem)
+		err = problemSnapshot.DataTo(problem)
+		if err != nil {
+			return nil, err
+		}
+
+		if cohortName(problem.Cohort) == cohort {
+			problem.ID = problemSnapshot.Ref.ID
+			latest, latestSnapshot = problem, problemSnapshot
+		}
+	}
+
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+
+	return latest, nil
+}
+
+func (s *firestoreProblemStore) Latest(ctx context.Context, cohort string) (*Problem, error) {
+	problemSnapshots := s.client.Collection("problems").OrderBy("createdAt", firestore.Desc).Documents(ctx)
+	defer problemSnapshots.Stop()
+
+	for {
+		problemSnapshot, err := problemSnapshots.Next()
+		if err == iterator.Done {
+			return nil, ErrNotFound
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		problem := new(Problem)
+		err = problemSnapshot.DataTo(problem)
+		if err != nil {
+			return nil, err
+		}
+
+		if cohortName(problem.Cohort) == cohort {
+			problem.ID = problemSnapshot.Ref.ID
+			return problem, nil
+		}
+	}
+}
+
+func (s *firestoreProblemStore) List(ctx context.Context) ([]*Problem, error) {
+	problemSnapshots, err := s.client.Collection("problems").OrderBy("createdAt", firestore.Desc).Documents(ctx).GetAll()
+	if err != nil {
+		return []*Problem{}, err
+	}
+
+	problems := []*Problem{}
+	for _, problemSnapshot := range problemSnapshots {
+		problem := new(Problem)
+		err = problemSnapshot.DataTo(problem)
+		if err != nil {
+			return []*Problem{}, err
+		}
+
+		problem.ID = problemSnapshot.Ref.ID
+		problem.CreatedAt = problemSnapshot.CreateTime
+		problems = append(problems, problem)
+	}
+
+	return problems, nil
+}
+
+func (s *firestoreProblemStore) Close(ctx context.Context, problemID ProblemID, majorityOptions []int) error {
+	data := map[string]interface{}{
+		"isOpen":   false,
+		"closedAt": firestore.ServerTimestamp,
+	}
+
+	if majorityOptions != nil {
+		data["majorityOptions"] = majorityOptions
+	}
+
+	_, err := s.client.Collection("problems").Doc(problemID).Set(ctx, data, firestore.MergeAll)
+	return err
+}
+
+func (s *firestoreProblemStore) SaveCalibration(ctx context.Context, problemID ProblemID, rating float64, difficulty int) error {
+	_, err := s.client.Collection("problems").Doc(problemID).Set(ctx, map[string]interface{}{
+		"rating":               rating,
+		"calibratedDifficulty": difficulty,
+		"calibratedAt":         firestore.ServerTimestamp,
+	}, firestore.MergeAll)
+
+	return err
+}
+
+type firestoreAnswerStore struct {
+	client     *firestore.Client
+	collection string
+}
+
+func NewFirestoreAnswerStore(client *firestore.Client) AnswerStore {
+	return &firestoreAnswerStore{client: client, collection: "answers"}
+}
+
+func NewFirestorePracticeAnswerStore(client *firestore.Client) AnswerStore {
+	return &firestoreAnswerStore{client: client, collection: "practiceAnswers"}
+}
+
+func (s *firestoreAnswerStore) Save(ctx context.Context, answer *Answer) error {
+	data := json_.ToMap(answer)
+	data["createdAt"] = firestore.ServerTimestamp
+	answerRef := s.client.Collection(s.collection).Doc(answerID(answer))
+	_, err := answerRef.Set(ctx, data)
+	if err != nil {
+		return err
+	}
+
+	answer.ID = answerRef.ID
+	return nil
+}
+
+func (s *firestoreAnswerStore) ListByProblem(ctx context.Context, problemID ProblemID) ([]*Answer, error) {
+	return s.list(ctx, s.client.Collection(s.collection).Where("problemID", "==", problemID))
+}
+
+func (s *firestoreAnswerStore) ListByUser(ctx context.Context, userID UserID) ([]*Answer, error) {
+	return s.list(ctx, s.client.Collection(s.collection).Where("userID", "==", userID))
+}
+
+func (s *firestoreAnswerStore) list(ctx context.Context, query firestore.Query) ([]*Answer, error) {
+	answerSnapshots, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return []*Answer{}, err
+	}
+
+	answers := []*Answer{}
+	for _, answerSnapshot := range answerSnapshots {
+		answer := new(Answer)
+		err = answerSnapshot.DataTo(answer)
+		if err != nil {
+			return []*Answer{}, err
+		}
+
+		answer.ID = answerSnapshot.Ref.ID
+		answers = append(answers, answer)
+	}
+
+	return answers, nil
+}
+
+type firestoreUserStore struct {
+	client *firestore.Client
+}
+
+func NewFirestoreUserStore(client *firestore.Client) UserStore {
+	return &firestoreUserStore{client: client}
+}
+
+func (s *firestoreUserStore) Get(ctx context.Context, userID UserID) (*User, error) {
+	userSnapshot, err := s.client.Doc("users/" + userID).Get(ctx)
+	if userSnapshot != nil && !userSnapshot.Exists() {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	user := new(User)
+	err = userSnapshot.DataTo(user)
+	if err != nil {
+		return nil, err
+	}
+
+	user.ID = userSnapshot.Ref.ID
+	return user, nil
+}
+
+func (s *firestoreUserStore) Create(ctx context.Context, user *User) error {
+	data := json_.ToMap(user)
+	data["createdAt"] = firestore.ServerTimestamp
+	_, err := s.client.Doc("users/"+user.ID).Set(ctx, data, firestore.MergeAll)
+	return err
+}
+
+func (s *firestoreUserStore) Update(ctx context.Context, userID UserID, fields map[string]interface{}) error {
+	_, err := s.client.Doc("users/"+userID).Set(ctx, fields, firestore.MergeAll)
+	return err
+}
+
+func (s *firestoreUserStore) ListReminded(ctx context.Context) ([]*User, error) {
+	return s.list(ctx, s.client.Collection("users").Where("isReminderEnabled", "==", true))
+}
+
+func (s *firestoreUserStore) ListReviewing(ctx context.Context) ([]*User, error) {
+	return s.list(ctx, s.client.Collection("users").Where("isReviewEnabled", "==", true))
+}
+
+func (s *firestoreUserStore) list(ctx context.Context, query firestore.Query) ([]*User, error) {
+	userSnapshots, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return []*User{}, err
+	}
+
+	users := []*User{}
+	for _, userSnapshot := range userSnapshots {
+		user := new(User)
+		err = userSnapshot.DataTo(user)
+		if err != nil {
+			return []*User{}, err
+		}
+
+		user.ID = userSnapshot.Ref.ID
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+type firestoreGroupStore struct {
+	client *firestore.Client
+}
+
+func NewFirestoreGroupStore(client *firestore.Client) GroupStore {
+	return &firestoreGroupStore{client: client}
+}
+
+func (s *firestoreGroupStore) Get(ctx context.Context, groupID GroupID) (*Group, error) {
+	groupSnapshot, err := s.client.Doc("groups/" + groupID).Get(ctx)
+	if groupSnapshot != nil && !groupSnapshot.Exists() {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	group := new(Group)
+	err = groupSnapshot.DataTo(group)
+	if err != nil {
+		return nil, err
+	}
+
+	group.ID = groupSnapshot.Ref.ID
+	return group, nil
+}
+
+func (s *firestoreGroupStore) List(ctx context.Context) ([]*Group, error) {
+	groupSnapshots, err := s.client.Collection("groups").Documents(ctx).GetAll()
+	if err != nil {
+		return []*Group{}, err
+	}
+
+	groups := []*Group{}
+	for _, groupSnapshot := range groupSnapshots {
+		group := new(Group)
+		err = groupSnapshot.DataTo(group)
+		if err != nil {
+			return []*Group{}, err
+		}
+
+		group.ID = groupSnapshot.Ref.ID
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+func (s *firestoreGroupStore) Save(ctx context.Context, group *Group) error {
+	data := json_.ToMap(group)
+	data["updatedAt"] = firestore.ServerTimestamp
+	_, err := s.client.Doc("groups/"+group.ID).Set(ctx, data, firestore.MergeAll)
+	return err
+}
+
+type firestoreReviewStore struct {
+	client *firestore.Client
+}
+
+func NewFirestoreReviewStore(client *firestore.Client) ReviewStore {
+	return &firestoreReviewStore{client: client}
+}
+
+func (s *firestoreReviewStore) Get(ctx context.Context, reviewID string) (*Review, error) {
+	reviewSnapshot, err := s.client.Doc("reviews/" + reviewID).Get(ctx)
+	if reviewSnapshot != nil && !reviewSnapshot.Exists() {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	review := new(Review)
+	err = reviewSnapshot.DataTo(review)
+	if err != nil {
+		return nil, err
+	}
+
+	review.ID = reviewSnapshot.Ref.ID
+	return review, nil
+}
+
+func (s *firestoreReviewStore) ListByUser(ctx context.Context, userID UserID) ([]*Review, error) {
+	reviewSnapshots, err := s.client.Collection("reviews").Where("userID", "==", userID).Documents(ctx).GetAll()
+	if err != nil {
+		return []*Review{}, err
+	}
+
+	reviews := []*Review{}
+	for _, reviewSnapshot := range reviewSnapshots {
+		review := new(Review)
+		err = reviewSnapshot.DataTo(review)
+		if err != nil {
+			return []*Review{}, err
+		}
+
+		review.ID = reviewSnapshot.Ref.ID
+		reviews = append(reviews, review)
+	}
+
+	return reviews, nil
+}
+
+func (s *firestoreReviewStore) Save(ctx context.Context, review *Review) error {
+	review.ID = reviewID(review.UserID, review.ProblemID)
+
+	data := json_.ToMap(review)
+	data["updatedAt"] = firestore.ServerTimestamp
+	_, err := s.client.Doc("reviews/"+review.ID).Set(ctx, data, firestore.MergeAll)
+	return err
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kuolc/oneLeg/json_"
+)
+
+func NewMemoryStores() *Stores {
+	return &Stores{
+		Problems:        NewMemoryProblemStore(),
+		Answers:         NewMemoryAnswerStore(),
+		PracticeAnswers: NewMemoryAnswerStore(),
+		Users:           NewMemoryUserStore(),
+		Groups:          NewMemoryGroupStore(),
+		Reviews:         NewMemoryReviewStore(),
+	}
+}
+
+type memoryProblemStore struct {
+	mutex    sync.Mutex
+	problems map[ProblemID]*Problem
+	order    []ProblemID
+	isOpen   map[ProblemID]bool
+}
+
+func NewMemoryProblemStore() ProblemStore {
+	return &memoryProblemStore{
+		problems: map[ProblemID]*Problem{},
+		isOpen:   map[ProblemID]bool{},
+	}
+}
+
+func (s *memoryProblemStore) Create(ctx context.Context, problem *Problem) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	problem.ID = strconv.Itoa(len(s.order) + 1)
+	problem.CreatedAt = time.Now()
+	copied := *problem
+	s.problems[problem.ID] = &copied
+	s.order = append(s.order, problem.ID)
+	s.isOpen[problem.ID] = true
+	return nil
+}
+
+func (s *memoryProblemStore) Get(ctx context.Context, problemID ProblemID) (*Problem, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	problem, ok := s.problems[problemID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	copied := *problem
+	return &copied, nil
+}
+
+func (s *memoryProblemStore) Open(ctx context.Context, cohort string) (*Problem, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for index := len(s.order) - 1; index >= 0; index-- {
+		problemID := s.order[index]
+		if s.isOpen[problemID] && cohortName(s.problems[problemID].Cohort) == cohort {
+			copied := *s.problems[problemID]
+			return &copied, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (s *memoryProblemStore) Latest(ctx context.Context, cohort string) (*Problem, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for index := len(s.order) - 1; index >= 0; index-- {
+		problem := s.problems[s.order[index]]
+		if cohortName(problem.Cohort) == cohort {
+			copied := *problem
+			return &copied, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (s *memoryProblemStore) List(ctx context.Context) ([]*Problem, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	problems := []*Problem{}
+	for index := len(s.order) - 1; index >= 0; index-- {
+		copied := *s.problems[s.order[index]]
+		problems = append(problems, &copied)
+	}
+
+	return problems, nil
+}
+
+func (s *memoryProblemStore) Close(ctx context.Context, problemID ProblemID, majorityOptions []int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	problem, ok := s.problems[problemID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if majorityOptions != nil {
+		problem.MajorityOptions = majorityOptions
+	}
+
+	s.isOpen[problemID] = false
+	return nil
+}
+
+func (s *memoryProblemStore) SaveCalibration(ctx context.Context, problemID ProblemID, rating float64, difficulty int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	problem, ok := s.problems[problemID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	problem.Rating = rating
+	problem.CalibratedDifficulty = difficulty
+	return nil
+}
+
+type memoryAnswerStore struct {
+	mutex   sync.Mutex
+	answers map[string]*Answer
+	order   []string
+}
+
+func NewMemoryAnswerStore() AnswerStore {
+	return &memoryAnswerStore{
+		answers: map[string]*Answer{},
+	}
+}
+
+func (s *memoryAnswerStore) Save(ctx context.Context, answer *Answer) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	answer.ID = answerID(answer)
+	if _, ok := s.answers[answer.ID]; !ok {
+		s.order = append(s.order, answer.ID)
+	}
+
+	copied := *answer
+	s.answers[answer.ID] = &copied
+	return nil
+}
+
+func (s *memoryAnswerStore) ListByProblem(ctx context.Context, problemID ProblemID) ([]*Answer, error) {
+	return s.list(func(answer *Answer) bool {
+		return answer.ProblemID == problemID
+	}), nil
+}
+
+func (s *memoryAnswerStore) ListByUser(ctx context.Context, userID UserID) ([]*Answer, error) {
+	return s.list(func(answer *Answer) bool {
+		return answer.UserID == userID
+	}), nil
+}
+
+func (s *memoryAnswerStore) list(match func(answer *Answer) bool) []*Answer {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	answers := []*Answer{}
+	for _, answerID := range s.order {
+		answer := s.answers[answerID]
+		if match(answer) {
+			copied := *answer
+			answers = append(answers, &copied)
+		}
+	}
+
+	return answers
+}
+
+type memoryUserStore struct {
+	mutex sync.Mutex
+	users map[UserID]*User
+}
+
+func NewMemoryUserStore() UserStore {
+	return &memoryUserStore{
+		users: map[UserID]*User{},
+	}
+}
+
+func (s *memoryUserStore) Get(ctx context.Context, userID UserID) (*User, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	copied := *user
+	return &copied, nil
+}
+
+func (s *memoryUserStore) Create(ctx context.Context, user *User) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	copied := *user
+	s.users[user.ID] = &copied
+	return nil
+}
+
+func (s *memoryUserStore) Update(ctx context.Context, userID UserID, fields map[string]interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user := &User{ID: userID}
+	if stored, ok := s.users[userID]; ok {
+		copied := *stored
+		user = &copied
+	}
+
+	data := json_.ToMap(user)
+	for key, value := range fields {
+		data[key] = value
+	}
+
+	err := json_.Cast(data, user)
+	if err != nil {
+		return err
+	}
+
+	s.users[userID] = user
+	return nil
+}
+
+func (s *memoryUserStore) ListReminded(ctx context.Context) ([]*User, error) {
+	return s.list(func(user *User) bool {
+		return user.IsReminderEnabled
+	}), nil
+}
+
+func (s *memoryUserStore) ListReviewing(ctx context.Context) ([]*User, error) {
+	return s.list(func(user *User) bool {
+		return user.IsReviewEnabled
+	}), nil
+}
+
+func (s *memoryUserStore) list(match func(user *User) bool) []*User {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	users := []*User{}
+	for _, user := range s.users {
+		if match(user) {
+			copied := *user
+			users = append(users, &copied)
+		}
+	}
+
+	return users
+}
+
+type memoryGroupStore struct {
+	mutex  sync.Mutex
+	groups map[GroupID]*Group
+}
+
+func NewMemoryGroupStore() GroupStore {
+	return &memoryGroupStore{
+		groups: map[GroupID]*Group{},
+	}
+}
+
+func (s *memoryGroupStore) Get(ctx context.Context, groupID GroupID) (*Group, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	group, ok := s.groups[groupID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	copied := *group
+	return &copied, nil
+}
+
+func (s *memoryGroupStore) List(ctx context.Context) ([]*Group, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	groups := []*Group{}
+	for _, group := range s.groups {
+		copied := *group
+		groups = append(groups, &copied)
+	}
+
+	return groups, nil
+}
+
+func (s *memoryGroupStore) Save(ctx context.Context, group *Group) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	copied := *group
+	copied.MemberIDs = append([]UserID{}, group.MemberIDs...)
+	s.groups[group.ID] = &copied
+	return nil
+}
+
+type memoryReviewStore struct {
+	mutex   sync.Mutex
+	reviews map[string]*Review
+}
+
+func NewMemoryReviewStore() ReviewStore {
+	return &memoryReviewStore{
+		reviews: map[string]*Review{},
+	}
+}
+
+func (s *memoryReviewStore) Get(ctx context.Context, reviewID string) (*Review, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	review, ok := s.reviews[reviewID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	copied := *review
+	return &copied, nil
+}
+
+func (s *memoryReviewStore) ListByUser(ctx context.Context, userID UserID) ([]*Review, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	reviews := []*Review{}
+	for _, review := range s.reviews {
+		if review.UserID == userID {
+			copied := *review
+			reviews = append(reviews, &copied)
+		}
+	}
+
+	return reviews, nil
+}
+
+func (s *memoryReviewStore) Save(ctx context.Context, review *Review) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	review.ID = reviewID(review.UserID, review.ProblemID)
+	copied := *review
+	s.reviews[review.ID] = &copied
+	return nil
+}